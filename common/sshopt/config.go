@@ -0,0 +1,347 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 17:40:26
+ */
+
+package sshopt
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lsne/goutils/environment"
+)
+
+// Include 的最大嵌套层数, 与 OpenSSH 相同
+const maxIncludeDepth = 16
+
+// SSHConfig ssh 客户端配置文件(~/.ssh/config)中的 Host 配置
+// 只解析 HostName, Port, User, IdentityFile, ProxyJump 和 Include, Match 块整体忽略
+type SSHConfig struct {
+	blocks []sshConfigBlock
+}
+
+type sshConfigBlock struct {
+	// Host 后的模式, ! 开头表示排除, 每一组都匹配时块中的配置才生效. 第一个 Host 之前的配置对所有机器生效, conds 为空
+	// Host 块中的 Include 引入的配置, 除了自身的 Host 外还要满足外层 Host 的模式
+	conds   [][]string
+	match   bool // Match 块(或 Match 块中 Include 的配置), 不支持, 忽略其中的配置
+	options [][2]string
+}
+
+// scope 返回与 b 生效条件相同、没有配置的块
+func (b sshConfigBlock) scope() sshConfigBlock {
+	return sshConfigBlock{conds: b.conds, match: b.match}
+}
+
+// SSHHostConfig 一台机器在 ssh 配置文件中的配置, 没有配置的字段为零值
+type SSHHostConfig struct {
+	HostName      string
+	Port          uint16
+	User          string
+	IdentityFiles []string
+	ProxyJump     string
+}
+
+// DefaultSSHConfigPath 当前用户的 ssh 配置文件 ~/.ssh/config
+func DefaultSSHConfigPath() string {
+	return filepath.Join(homeDir(), ".ssh", "config")
+}
+
+// LoadDefaultSSHConfig 加载 ~/.ssh/config, 文件不存在时返回空配置
+func LoadDefaultSSHConfig() (*SSHConfig, error) {
+	file := DefaultSSHConfigPath()
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		return &SSHConfig{}, nil
+	}
+	return LoadSSHConfig(file)
+}
+
+// LoadSSHConfig 加载 ssh 配置文件. Include 的相对路径相对于 ~/.ssh 目录
+func LoadSSHConfig(file string) (*SSHConfig, error) {
+	c := &SSHConfig{}
+	if err := c.load(file, 0, sshConfigBlock{}); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// load 加载 file 中的配置, scope 为 Include 所在块的生效条件, file 中 Host 之前的配置以及 Host 块都要满足该条件
+func (c *SSHConfig) load(file string, depth int, scope sshConfigBlock) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("ssh 配置文件(%s) Include 嵌套超过 %d 层", file, maxIncludeDepth)
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("打开 ssh 配置文件(%s)失败: %v", file, err)
+	}
+	defer f.Close()
+
+	// 当前块在 c.blocks 中的位置, Include 引入的块追加在后面, 之后的配置追加到与当前块条件相同的新块中, 保持配置的先后顺序
+	c.blocks = append(c.blocks, scope.scope())
+	current := len(c.blocks) - 1
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		keyword, args, err := parseConfigLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("ssh 配置文件(%s)第 %d 行格式不合法: %v", file, n, err)
+		}
+
+		switch keyword {
+		case "":
+		case "host":
+			conds := append(slices.Clip(scope.conds), args)
+			c.blocks = append(c.blocks, sshConfigBlock{conds: conds, match: scope.match})
+			current = len(c.blocks) - 1
+		case "match":
+			c.blocks = append(c.blocks, sshConfigBlock{match: true})
+			current = len(c.blocks) - 1
+		case "include":
+			parent := c.blocks[current].scope()
+			if err := c.include(args, depth, parent); err != nil {
+				return err
+			}
+			c.blocks = append(c.blocks, parent)
+			current = len(c.blocks) - 1
+		default:
+			if len(args) == 0 {
+				return fmt.Errorf("ssh 配置文件(%s)第 %d 行 %s 缺少参数", file, n, keyword)
+			}
+			block := &c.blocks[current]
+			for _, arg := range args {
+				block.options = append(block.options, [2]string{keyword, arg})
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *SSHConfig) include(patterns []string, depth int, scope sshConfigBlock) error {
+	for _, pattern := range patterns {
+		pattern = expandHome(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(homeDir(), ".ssh", pattern)
+		}
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("ssh 配置文件 Include(%s) 格式不合法: %v", pattern, err)
+		}
+		for _, file := range files {
+			if err := c.load(file, depth+1, scope); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseConfigLine 解析 "Keyword args" 或 "Keyword=args" 格式的一行, 返回小写的关键字和参数, 参数可以用双引号包含空格
+func parseConfigLine(line string) (string, []string, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", nil, nil
+	}
+
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return strings.ToLower(line), nil, nil
+	}
+	keyword := strings.ToLower(line[:i])
+	rest := strings.TrimLeft(line[i:], " \t")
+	rest = strings.TrimLeft(strings.TrimPrefix(rest, "="), " \t")
+
+	var args []string
+	for rest != "" {
+		var arg string
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return "", nil, fmt.Errorf("引号不匹配")
+			}
+			arg, rest = rest[1:end+1], rest[end+2:]
+		} else if end := strings.IndexAny(rest, " \t"); end >= 0 {
+			arg, rest = rest[:end], rest[end:]
+		} else {
+			arg, rest = rest, ""
+		}
+		if strings.HasPrefix(arg, "#") {
+			break
+		}
+		args = append(args, arg)
+		rest = strings.TrimLeft(rest, " \t")
+	}
+	return keyword, args, nil
+}
+
+// Lookup 返回 alias 的配置. 与 ssh 相同, 每个选项使用第一个匹配的值, IdentityFile 累加
+func (c *SSHConfig) Lookup(alias string) SSHHostConfig {
+	var hc SSHHostConfig
+	var port string
+	for _, block := range c.blocks {
+		if !block.matches(alias) {
+			continue
+		}
+		for _, opt := range block.options {
+			switch value := opt[1]; opt[0] {
+			case "hostname":
+				setOnce(&hc.HostName, value)
+			case "port":
+				setOnce(&port, value)
+			case "user":
+				setOnce(&hc.User, value)
+			case "identityfile":
+				hc.IdentityFiles = append(hc.IdentityFiles, value)
+			case "proxyjump":
+				setOnce(&hc.ProxyJump, value)
+			}
+		}
+	}
+
+	if hc.HostName != "" {
+		hc.HostName = strings.ReplaceAll(hc.HostName, "%h", alias)
+	}
+	if p, err := strconv.ParseUint(port, 10, 16); err == nil {
+		hc.Port = uint16(p)
+	}
+	if strings.EqualFold(hc.ProxyJump, "none") {
+		hc.ProxyJump = ""
+	}
+
+	host := alias
+	if hc.HostName != "" {
+		host = hc.HostName
+	}
+	for i, file := range hc.IdentityFiles {
+		hc.IdentityFiles[i] = expandTokens(file, host, hc.User)
+	}
+	return hc
+}
+
+// matches 块中的配置是否对 alias 生效
+func (b sshConfigBlock) matches(alias string) bool {
+	if b.match {
+		return false
+	}
+	for _, patterns := range b.conds {
+		if !matchHost(patterns, alias) {
+			return false
+		}
+	}
+	return true
+}
+
+func setOnce(dst *string, value string) {
+	if *dst == "" {
+		*dst = value
+	}
+}
+
+// matchHost 任意一个模式匹配且没有排除模式匹配时返回 true, 模式支持 * 和 ?, 不区分大小写
+func matchHost(patterns []string, host string) bool {
+	host = strings.ToLower(host)
+	matched := false
+	for _, pattern := range patterns {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.ToLower(strings.TrimPrefix(pattern, "!"))
+		// ssh 的模式中没有字符类, 转义 path.Match 中的特殊字符
+		pattern = strings.NewReplacer(`\`, `\\`, "[", `\[`).Replace(pattern)
+		if ok, _ := path.Match(pattern, host); ok {
+			if negate {
+				return false
+			}
+			matched = true
+		}
+	}
+	return matched
+}
+
+// expandTokens 展开 IdentityFile 中的 ~ 和 %d(家目录), %h(机器), %r(远程用户), %u(本地用户), %%
+func expandTokens(s string, host, user string) string {
+	s = expandHome(s)
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	return strings.NewReplacer(
+		"%%", "%",
+		"%d", homeDir(),
+		"%h", host,
+		"%r", user,
+		"%u", os.Getenv("USER"),
+	).Replace(s)
+}
+
+// homeDir 优先使用全局环境变量中的家目录, 未初始化时使用 os.UserHomeDir
+func homeDir() string {
+	if env := environment.GlobalEnv(); env != nil && env.HomePath != "" {
+		return env.HomePath
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+func expandHome(s string) string {
+	if s == "~" || strings.HasPrefix(s, "~/") {
+		return filepath.Join(homeDir(), s[1:])
+	}
+	return s
+}
+
+// identityFiles 返回 hc 中可以使用的 IdentityFile
+// 与 ssh 相同, 跳过不存在、无法解析或已加密但没有提供 passphrase 的私钥, 如 Host * 中配置的默认私钥
+func identityFiles(hc SSHHostConfig, passphrase string) []string {
+	var files []string
+	for _, file := range hc.IdentityFiles {
+		if loadableKey(file, passphrase) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// ApplySSHConfig 将 c 中 o.Host 的配置填充到 o 中, o 中已经设置的字段优先
+// Host 替换为配置的 HostName, 第一个可以使用的 IdentityFile 作为 KeyFile(KeyFile 未设置时), 其余追加到 KeyFiles
+// ProxyJump 中的跳板机别名在 JumpHostList 中同样通过 c 解析
+func (o *SshOptions) ApplySSHConfig(c *SSHConfig) {
+	o.sshConfig = c
+	hc := c.Lookup(o.Host)
+	if hc.HostName != "" {
+		o.Host = hc.HostName
+	}
+	if o.Port == 0 {
+		o.Port = hc.Port
+	}
+	if o.Username == "" {
+		o.Username = hc.User
+	}
+	if o.ProxyJump == "" {
+		o.ProxyJump = hc.ProxyJump
+	}
+	for _, file := range identityFiles(hc, o.Passphrase) {
+		switch {
+		case file == o.KeyFile:
+		case o.KeyFile == "" && o.Password == "":
+			o.KeyFile = file
+		default:
+			o.KeyFiles = append(o.KeyFiles, file)
+		}
+	}
+}
+
+// ResolveAlias 使用 ~/.ssh/config 解析机器别名, 返回填充了 HostName, Port, User, IdentityFile, ProxyJump 的选项
+func ResolveAlias(alias string) (SshOptions, error) {
+	o := SshOptions{Host: alias}
+	c, err := LoadDefaultSSHConfig()
+	if err != nil {
+		return o, err
+	}
+	o.ApplySSHConfig(c)
+	return o, nil
+}
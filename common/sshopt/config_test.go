@@ -0,0 +1,181 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-19 17:20:46
+ */
+
+package sshopt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lsne/goutils/utils/gossh"
+	"golang.org/x/crypto/ssh"
+)
+
+const testSSHConfig = `
+Host db-* !db-bad
+    HostName %h.example.com
+    Port 2222
+    IdentityFile ~/.ssh/id_db
+Host db-1
+    Port 3333
+    User dbuser
+Match host db-1
+    User matched
+Host web
+    Include web.conf
+    Port 2200
+Host bastion
+    HostName 10.0.0.9
+    User jump
+    Port=2022
+    IdentityFile "~/.ssh/id_jump"
+Host *
+    User default
+    IdentityFile %d/.ssh/id_%r
+`
+
+const testSSHConfigWeb = `
+HostName web.internal
+Host web-extra
+    User extra
+`
+
+func loadTestSSHConfig(t *testing.T) (*SSHConfig, string) {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".ssh")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"config": testSSHConfig, "web.conf": testSSHConfigWeb} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := LoadSSHConfig(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatalf("加载 ssh 配置文件失败: %v", err)
+	}
+	return c, dir
+}
+
+func TestSSHConfigLookup(t *testing.T) {
+	c, dir := loadTestSSHConfig(t)
+
+	tests := []struct {
+		alias string
+		want  SSHHostConfig
+	}{
+		// 第一个匹配的值生效, IdentityFile 累加, %h 和 ~ 展开, Match 块忽略
+		{"db-1", SSHHostConfig{HostName: "db-1.example.com", Port: 2222, User: "dbuser",
+			IdentityFiles: []string{filepath.Join(dir, "id_db"), filepath.Join(dir, "id_dbuser")}}},
+		// 排除模式
+		{"db-bad", SSHHostConfig{User: "default", IdentityFiles: []string{filepath.Join(dir, "id_default")}}},
+		// Host 块中 Include 的配置只对该 Host 生效, Include 之后的配置仍属于该 Host
+		{"web", SSHHostConfig{HostName: "web.internal", Port: 2200, User: "default",
+			IdentityFiles: []string{filepath.Join(dir, "id_default")}}},
+		{"web-extra", SSHHostConfig{User: "default", IdentityFiles: []string{filepath.Join(dir, "id_default")}}},
+		{"BASTION", SSHHostConfig{HostName: "10.0.0.9", Port: 2022, User: "jump",
+			IdentityFiles: []string{filepath.Join(dir, "id_jump"), filepath.Join(dir, "id_jump")}}},
+	}
+	for _, tt := range tests {
+		if got := c.Lookup(tt.alias); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Lookup(%s):\n实际: %+v\n期望: %+v", tt.alias, got, tt.want)
+		}
+	}
+}
+
+func TestSSHConfigInclude(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	file := filepath.Join(home, "config")
+
+	// Include 自身, 超过嵌套层数
+	if err := os.WriteFile(file, []byte("Include "+file+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSSHConfig(file); err == nil {
+		t.Error("Include 嵌套超过限制时应返回错误")
+	}
+
+	if err := os.WriteFile(file, []byte("Host a\n  HostName \"unterminated\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSSHConfig(file); err == nil {
+		t.Error("引号不匹配时应返回错误")
+	}
+
+	// Match 块中的 Include 整体忽略, 不存在的 Include 文件忽略
+	if err := os.WriteFile(filepath.Join(home, "inc"), []byte("User included\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	content := "Include none-*.conf\nMatch all\n  Include " + filepath.Join(home, "inc") + "\nHost *\n  User star\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := LoadSSHConfig(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hc := c.Lookup("x"); hc.User != "star" {
+		t.Errorf("Match 块中 Include 的配置应忽略, 实际: %+v", hc)
+	}
+}
+
+func TestApplySSHConfig(t *testing.T) {
+	c, dir := loadTestSSHConfig(t)
+
+	// 只生成 id_db, id_jump, id_default, 不存在的 id_dbuser 应被跳过
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"id_db", "id_jump", "id_default"} {
+		if err := os.WriteFile(filepath.Join(dir, name), pem.EncodeToMemory(block), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	o := SshOptions{Host: "db-1", Port: 22, ProxyJump: "bastion, ssh://admin@[::1]:2200,root@web"}
+	o.ApplySSHConfig(c)
+	if o.Host != "db-1.example.com" || o.Port != 22 || o.Username != "dbuser" || o.KeyFile != filepath.Join(dir, "id_db") || len(o.KeyFiles) != 0 {
+		t.Errorf("ApplySSHConfig 结果不正确: %+v", o)
+	}
+	if err := o.ValidateKeyFiles(); err != nil {
+		t.Errorf("不应包含不可用的私钥: %v", err)
+	}
+
+	// ProxyJump 中的别名同样通过 ssh 配置解析, 指定的用户和端口优先
+	hops, err := o.JumpHostList()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gossh.JumpHost{
+		{Host: "10.0.0.9", Port: 2022, User: "jump", KeyFile: filepath.Join(dir, "id_jump")},
+		{Host: "::1", Port: 2200, User: "admin", KeyFile: filepath.Join(dir, "id_default")},
+		{Host: "web.internal", Port: 2200, User: "root", KeyFile: filepath.Join(dir, "id_default")},
+	}
+	if !reflect.DeepEqual(hops, want) {
+		t.Errorf("跳板机解析不正确:\n实际: %+v\n期望: %+v", hops, want)
+	}
+
+	// 没有使用 ssh 配置时不解析别名
+	o = SshOptions{ProxyJump: "bastion:2022"}
+	if hops, err := o.JumpHostList(); err != nil || !reflect.DeepEqual(hops, []gossh.JumpHost{{Host: "bastion", Port: 2022}}) {
+		t.Errorf("跳板机解析不正确: %+v, %v", hops, err)
+	}
+}
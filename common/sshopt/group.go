@@ -0,0 +1,65 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 13:52:40
+ */
+
+package sshopt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/lsne/goutils/utils/gossh"
+)
+
+// NewConnection 使用 ssh 连接选项建立连接, 配置了跳板机时经由跳板机连接
+func (o *SshOptions) NewConnection(timeout int64, opts ...gossh.ConnOption) (*gossh.Connection, error) {
+	hops, err := o.JumpHostList()
+	if err != nil {
+		return nil, err
+	}
+	connOpts := []gossh.ConnOption{gossh.WithKeyFiles(o.KeyFiles...), gossh.WithPassphrase(o.Passphrase), gossh.WithAgent(o.UseAgent)}
+	if len(hops) > 0 {
+		connOpts = append(connOpts, gossh.WithJumpHosts(hops...))
+	}
+	opts = append(connOpts, opts...)
+
+	conn, err := gossh.NewConnection(o.Host, o.Port, o.Username, o.Password, o.KeyFile, timeout, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("连接机器 %s 失败: %w", o.Host, err)
+	}
+	return conn, nil
+}
+
+// NewGroup 并发连接所有机器, 返回 gossh.Group. 任意一台机器连接失败或 user@host:port 重复时, 关闭已建立的连接并返回错误
+func NewGroup(options []SshOptions, timeout int64, opts ...gossh.ConnOption) (*gossh.Group, error) {
+	conns := make([]*gossh.Connection, len(options))
+	errs := make([]error, len(options))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, gossh.DefaultConcurrency)
+	for i := range options {
+		wg.Go(func() {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			conns[i], errs[i] = options[i].NewConnection(timeout, opts...)
+		})
+	}
+	wg.Wait()
+
+	err := errors.Join(errs...)
+	var g *gossh.Group
+	if err == nil {
+		g, err = gossh.NewGroup(conns...)
+	}
+	if err != nil {
+		for _, conn := range conns {
+			if conn != nil {
+				conn.Close()
+			}
+		}
+		return nil, err
+	}
+	return g, nil
+}
@@ -0,0 +1,99 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 17:05:36
+ */
+
+package sshopt
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lsne/goutils/utils/gossh"
+	"github.com/lsne/goutils/utils/netutil"
+)
+
+// JumpHostOptions 跳板机连接选项, 为空的字段使用目标机器的配置
+type JumpHostOptions struct {
+	Host     string `yaml:"host"`
+	Port     uint16 `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	KeyFile  string `yaml:"keyfile"`
+}
+
+// JumpHostList 合并 ProxyJump 和 JumpHosts 中配置的跳板机, ProxyJump 在前
+func (o *SshOptions) JumpHostList() ([]gossh.JumpHost, error) {
+	var hops []gossh.JumpHost
+
+	for s := range strings.SplitSeq(o.ProxyJump, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		t, err := ParseTarget(s)
+		if err != nil {
+			return nil, fmt.Errorf("跳板机(%s)格式不合法: %w", s, err)
+		}
+		hops = append(hops, o.resolveJumpHost(t))
+	}
+
+	for _, j := range o.JumpHosts {
+		hops = append(hops, gossh.JumpHost{Host: j.Host, Port: j.Port, User: j.Username, Password: j.Password, KeyFile: j.KeyFile})
+	}
+	return hops, nil
+}
+
+// resolveJumpHost 与目标机器相同, 通过 ApplySSHConfig 的配置解析跳板机的别名, ProxyJump 中指定的用户和端口优先
+func (o *SshOptions) resolveJumpHost(t SshOptions) gossh.JumpHost {
+	hop := gossh.JumpHost{Host: t.Host, Port: t.Port, User: t.Username, Password: t.Password}
+	if o.sshConfig == nil {
+		return hop
+	}
+
+	hc := o.sshConfig.Lookup(t.Host)
+	if hc.HostName != "" {
+		hop.Host = hc.HostName
+	}
+	if hop.Port == 0 {
+		hop.Port = hc.Port
+	}
+	if hop.User == "" {
+		hop.User = hc.User
+	}
+	if files := identityFiles(hc, o.Passphrase); len(files) > 0 {
+		hop.KeyFile = files[0]
+	}
+	return hop
+}
+
+func (o *SshOptions) ValidateJumpHosts() error {
+	hops, err := o.JumpHostList()
+	if err != nil {
+		return err
+	}
+	for _, hop := range hops {
+		if !netutil.ValidHostnameOrIP(hop.Host) {
+			return fmt.Errorf("跳板机 (%s) 既不是合法的 IP 地址, 也不是合法的主机名", hop.Host)
+		}
+	}
+	return nil
+}
+
+// splitUserHostPort 拆分 [user@]host[:port] 格式的字符串, IPv6 地址带端口时需要使用 [host]:port 格式
+func splitUserHostPort(s string) (user, host string, port uint16, err error) {
+	if i := strings.LastIndex(s, "@"); i >= 0 {
+		user, s = s[:i], s[i+1:]
+	}
+
+	if strings.HasPrefix(s, "[") || strings.Count(s, ":") == 1 {
+		if host, port, err = netutil.SplitHostPort(s); err != nil {
+			return "", "", 0, err
+		}
+		return user, host, port, nil
+	}
+
+	if !netutil.ValidHostnameOrIP(s) {
+		return "", "", 0, fmt.Errorf("(%s) 既不是合法的 IP 地址, 也不是合法的主机名", s)
+	}
+	return user, s, 0, nil
+}
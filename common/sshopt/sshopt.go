@@ -6,18 +6,49 @@
 package sshopt
 
 import (
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"runtime"
 	"slices"
 	"strings"
 
 	"github.com/lsne/goutils/common/system"
-	"github.com/lsne/goutils/environment"
 	"github.com/lsne/goutils/utils/gocmd"
 	"github.com/lsne/goutils/utils/netutil"
+	"golang.org/x/crypto/ssh"
 )
 
-var keyfile = filepath.Join(environment.GlobalEnv().HomePath, ".ssh", "id_rsa")
+// 未指定密码和私钥时, 按顺序尝试的默认私钥
+var defaultKeyNames = []string{"id_ed25519", "id_ecdsa", "id_rsa"}
+
+// defaultKeyFiles 返回所有可以加载的默认私钥, 加密的私钥只有提供了正确的 passphrase 时才返回, 都不可用时返回空
+// 不能在包初始化时计算, 此时全局环境变量可能还未设置
+func defaultKeyFiles(passphrase string) []string {
+	var keyfiles []string
+	for _, name := range defaultKeyNames {
+		keyfile := filepath.Join(homeDir(), ".ssh", name)
+		if loadableKey(keyfile, passphrase) {
+			keyfiles = append(keyfiles, keyfile)
+		}
+	}
+	return keyfiles
+}
+
+// loadableKey 私钥文件是否存在且可以解析
+func loadableKey(keyfile string, passphrase string) bool {
+	data, err := os.ReadFile(keyfile)
+	if err != nil {
+		return false
+	}
+	_, err = ssh.ParseRawPrivateKey(data)
+	var missingErr *ssh.PassphraseMissingError
+	if errors.As(err, &missingErr) && passphrase != "" {
+		_, err = ssh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	return err == nil
+}
 
 // SshOptions ssh连接选项
 type SshOptions struct {
@@ -27,6 +58,17 @@ type SshOptions struct {
 	Password string `yaml:"password" ini:"ssh-password"`
 	KeyFile  string `yaml:"keyfile" ini:"ssh-keyfile"`
 	TmpDir   string `yaml:"tmp-dir" ini:"tmp-dir"`
+
+	KeyFiles   []string `yaml:"keyfiles" ini:"ssh-keyfiles"`     // 除 KeyFile 外的其他私钥文件, ini 中以逗号分隔
+	Passphrase string   `yaml:"passphrase" ini:"ssh-passphrase"` // 加密私钥的密码
+	UseAgent   bool     `yaml:"use-agent" ini:"ssh-use-agent"`   // 是否使用 SSH_AUTH_SOCK 指向的 ssh-agent
+
+	// 跳板机, 格式与 ssh -J 相同: [user@]host[:port][,[user@]host[:port]...], 也可以是 ssh://user@host:port, 密码和密钥使用上面的配置
+	ProxyJump string `yaml:"proxy-jump" ini:"ssh-proxy-jump"`
+	// 需要单独指定用户名、密码或密钥的跳板机, 只支持 yaml 配置, 排在 ProxyJump 之后
+	JumpHosts []JumpHostOptions `yaml:"jump-hosts" ini:"-"`
+
+	sshConfig *SSHConfig // ApplySSHConfig 使用的配置, 用于解析 ProxyJump 中的别名
 }
 
 func (o *SshOptions) SetDefault(tmpdir string) {
@@ -38,8 +80,14 @@ func (o *SshOptions) SetDefault(tmpdir string) {
 		o.TmpDir = tmpdir
 	}
 
-	if o.Password == "" && o.KeyFile == "" {
-		o.KeyFile = keyfile
+	// 与 ssh 相同, 依次尝试所有可以加载的默认私钥(使用 ssh-agent 时 agent 中的密钥优先), 跳过没有提供 Passphrase 的加密私钥
+	if o.Password == "" && o.KeyFile == "" && len(o.KeyFiles) == 0 {
+		if keyfiles := defaultKeyFiles(o.Passphrase); len(keyfiles) > 0 {
+			o.KeyFile, o.KeyFiles = keyfiles[0], keyfiles[1:]
+		} else if !o.UseAgent {
+			// 默认私钥都不可用且不使用 ssh-agent 时, 保持原来的行为, 连接时报 id_rsa 不存在
+			o.KeyFile = filepath.Join(homeDir(), ".ssh", "id_rsa")
+		}
 	}
 }
 
@@ -57,6 +105,12 @@ func (o *SshOptions) Validate() error {
 	if err := o.ValidateUsername(); err != nil {
 		return err
 	}
+	if err := o.ValidateJumpHosts(); err != nil {
+		return err
+	}
+	if err := o.ValidateKeyFiles(); err != nil {
+		return err
+	}
 	return o.ValidateTmpDir()
 }
 
@@ -84,6 +138,33 @@ func (o *SshOptions) ValidateUsername() error {
 	return nil
 }
 
+// ValidateKeyFiles 检查 KeyFile 和 KeyFiles 是否存在、可读, 且与 ssh 的要求相同, 不能被其他用户访问
+func (o *SshOptions) ValidateKeyFiles() error {
+	for _, keyfile := range append([]string{o.KeyFile}, o.KeyFiles...) {
+		if keyfile == "" {
+			continue
+		}
+
+		info, err := os.Stat(keyfile)
+		if err != nil {
+			return fmt.Errorf("私钥文件(%s)不可用: %v", keyfile, err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("私钥文件(%s)不是普通文件", keyfile)
+		}
+		if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+			return fmt.Errorf("私钥文件(%s)的权限(%04o)过于开放, 不能被其他用户访问, 请修改为 0600", keyfile, info.Mode().Perm())
+		}
+
+		f, err := os.Open(keyfile)
+		if err != nil {
+			return fmt.Errorf("私钥文件(%s)不可读: %v", keyfile, err)
+		}
+		f.Close()
+	}
+	return nil
+}
+
 func (o *SshOptions) ValidateTmpDir() error {
 	if o.TmpDir == "" {
 		return fmt.Errorf("临时目录不能为空")
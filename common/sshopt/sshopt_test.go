@@ -0,0 +1,97 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-20 10:12:37
+ */
+
+package sshopt
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// writeTestKeys 在临时家目录中生成默认私钥: 未加密的 id_ed25519, 加密的 id_ecdsa, 无法解析的 id_rsa
+func writeTestKeys(t *testing.T, passphrase string) string {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, ".ssh")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edBlock, err := ssh.MarshalPrivateKey(edKey, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecBlock, err := ssh.MarshalPrivateKeyWithPassphrase(ecKey, "", []byte(passphrase))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range map[string][]byte{
+		"id_ed25519": pem.EncodeToMemory(edBlock),
+		"id_ecdsa":   pem.EncodeToMemory(ecBlock),
+		"id_rsa":     []byte("not a private key"),
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestSetDefaultKeyFiles(t *testing.T) {
+	dir := writeTestKeys(t, "secret")
+
+	cases := []struct {
+		name     string
+		opts     SshOptions
+		keyFile  string
+		keyFiles []string
+	}{
+		{"跳过加密私钥和无法解析的私钥", SshOptions{}, filepath.Join(dir, "id_ed25519"), []string{}},
+		{"提供 Passphrase 时使用加密私钥", SshOptions{Passphrase: "secret"}, filepath.Join(dir, "id_ed25519"), []string{filepath.Join(dir, "id_ecdsa")}},
+		{"Passphrase 错误时跳过加密私钥", SshOptions{Passphrase: "wrong"}, filepath.Join(dir, "id_ed25519"), []string{}},
+		{"指定密码时不使用默认私钥", SshOptions{Password: "p"}, "", nil},
+		{"指定私钥时不使用默认私钥", SshOptions{KeyFile: "/tmp/id_test"}, "/tmp/id_test", nil},
+	}
+	for _, c := range cases {
+		o := c.opts
+		o.SetDefault("/tmp")
+		if o.KeyFile != c.keyFile || !reflect.DeepEqual(o.KeyFiles, c.keyFiles) {
+			t.Errorf("%s: KeyFile, KeyFiles 应为 %q, %q, 实际: %q, %q", c.name, c.keyFile, c.keyFiles, o.KeyFile, o.KeyFiles)
+		}
+	}
+
+	// 默认私钥都不可用时, 使用 ssh-agent 则不设置私钥, 否则保持原来的行为使用 id_rsa
+	if err := os.Remove(filepath.Join(dir, "id_ed25519")); err != nil {
+		t.Fatal(err)
+	}
+	o := SshOptions{UseAgent: true}
+	if o.SetDefault("/tmp"); o.KeyFile != "" || len(o.KeyFiles) != 0 {
+		t.Errorf("使用 ssh-agent 且默认私钥都不可用时不应设置私钥, 实际: %q, %q", o.KeyFile, o.KeyFiles)
+	}
+	o = SshOptions{}
+	if o.SetDefault("/tmp"); o.KeyFile != filepath.Join(dir, "id_rsa") {
+		t.Errorf("默认私钥都不可用时应使用 id_rsa, 实际: %q", o.KeyFile)
+	}
+}
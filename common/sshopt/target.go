@@ -0,0 +1,80 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 18:12:53
+ */
+
+package sshopt
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/lsne/goutils/utils/netutil"
+)
+
+// ParseURI 解析 ssh://[user[:password]@]host[:port] 格式的地址, 不允许包含路径和参数
+func ParseURI(s string) (SshOptions, error) {
+	var o SshOptions
+	u, err := url.Parse(s)
+	if err != nil {
+		return o, fmt.Errorf("ssh 地址(%s)格式不合法: %v", s, err)
+	}
+	if u.Scheme != "ssh" {
+		return o, fmt.Errorf("ssh 地址(%s)必须以 ssh:// 开头", s)
+	}
+	if strings.Trim(u.Path, "/") != "" || u.RawQuery != "" || u.Fragment != "" {
+		return o, fmt.Errorf("ssh 地址(%s)不能包含路径和参数", s)
+	}
+
+	o.Host = u.Hostname()
+	if !netutil.ValidHostnameOrIP(o.Host) {
+		return o, fmt.Errorf("ssh 地址(%s)中的 (%s) 既不是合法的 IP 地址, 也不是合法的主机名", s, o.Host)
+	}
+	if port := u.Port(); port != "" {
+		p, err := strconv.ParseUint(port, 10, 16)
+		if err != nil || p == 0 {
+			return o, fmt.Errorf("ssh 地址(%s)中的端口(%s)不合法", s, port)
+		}
+		o.Port = uint16(p)
+	}
+	if u.User != nil {
+		o.Username = u.User.Username()
+		o.Password, _ = u.User.Password()
+	}
+	return o, nil
+}
+
+// ParseTarget 解析 ssh://user@host:port 或 [user@]host[:port] 格式的地址, IPv6 地址带端口时需要使用 [host]:port 格式
+func ParseTarget(s string) (SshOptions, error) {
+	if strings.HasPrefix(s, "ssh://") {
+		return ParseURI(s)
+	}
+
+	user, host, port, err := splitUserHostPort(s)
+	if err != nil {
+		return SshOptions{}, fmt.Errorf("ssh 地址(%s)格式不合法: %w", s, err)
+	}
+	return SshOptions{Host: host, Port: port, Username: user}, nil
+}
+
+// ExpandTargets 展开以空白分隔的多个地址, 每个地址的格式同 ParseTarget, 并支持 netutil.ExpandHostRange 的范围,
+// 如 "user@10.0.0.[1-5]:2222" 展开为 5 个 SshOptions
+func ExpandTargets(s string) ([]SshOptions, error) {
+	var options []SshOptions
+	for _, field := range strings.Fields(s) {
+		targets, err := netutil.ExpandHostRange(field)
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range targets {
+			o, err := ParseTarget(target)
+			if err != nil {
+				return nil, err
+			}
+			options = append(options, o)
+		}
+	}
+	return options, nil
+}
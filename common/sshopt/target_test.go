@@ -0,0 +1,66 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-19 17:45:03
+ */
+
+package sshopt
+
+import (
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		in   string
+		want SshOptions
+		err  bool
+	}{
+		{in: "ssh://root:pw@10.0.0.1:2222", want: SshOptions{Host: "10.0.0.1", Port: 2222, Username: "root", Password: "pw"}},
+		{in: "ssh://[::1]:22", want: SshOptions{Host: "::1", Port: 22}},
+		{in: "ssh://[::1]", want: SshOptions{Host: "::1"}},
+		{in: "ssh://db.example.com/", want: SshOptions{Host: "db.example.com"}},
+		{in: "ssh://root@db.example.com", want: SshOptions{Host: "db.example.com", Username: "root"}},
+		{in: "ssh://db/data", err: true},
+		{in: "ssh://db?x=1", err: true},
+		{in: "ssh://db:0", err: true},
+		{in: "ssh://db:70000", err: true},
+		{in: "ssh://bad_host!", err: true},
+		{in: "sftp://db", err: true},
+		{in: "root@[::1]:22", want: SshOptions{Host: "::1", Port: 22, Username: "root"}},
+		{in: "::1", want: SshOptions{Host: "::1"}},
+		{in: "10.0.0.1:2222", want: SshOptions{Host: "10.0.0.1", Port: 2222}},
+		{in: "db", want: SshOptions{Host: "db"}},
+		{in: "db:port", err: true},
+		{in: "bad host", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTarget(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("ParseTarget(%s) 应返回错误, 实际: %+v", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got.Host != tt.want.Host || got.Port != tt.want.Port || got.Username != tt.want.Username || got.Password != tt.want.Password {
+			t.Errorf("ParseTarget(%s) = %+v, %v, 期望: %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestExpandTargets(t *testing.T) {
+	options, err := ExpandTargets("root@10.0.0.[1-2]:2222  ssh://[::1]:22 db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, o := range options {
+		got = append(got, o.Username+"@"+o.Host)
+	}
+	if len(options) != 4 || options[1].Host != "10.0.0.2" || options[1].Port != 2222 || options[2].Host != "::1" || options[3].Host != "db" {
+		t.Errorf("展开结果不正确: %v", got)
+	}
+
+	if _, err := ExpandTargets("10.0.0.[2-1]"); err == nil {
+		t.Error("范围不合法时应返回错误")
+	}
+}
@@ -8,23 +8,42 @@ package system
 import (
 	"fmt"
 
+	"github.com/lsne/goutils/utils/executor"
 	"github.com/lsne/goutils/utils/gocmd"
 )
 
+// ChownAll 以当前用户在本机执行 chown -R, 不经过 sudo
 func ChownAll(path, user, group string) error {
-	cmd := fmt.Sprintf("chown -R %s:%s %s", user, group, path)
-	sh := gocmd.Shell{}
-	if _, stderr, err := sh.Run(cmd); err != nil {
-		return fmt.Errorf("修改数据目录所属用户失败: %v, 标准错误输出: %s", err, stderr)
+	return chownAll(executor.NewLocal().RunCommand, path, user, group)
+}
+
+// ChownAllOn 在指定的机器上以 sudo 递归修改 path 的所属用户和组
+func ChownAllOn(e executor.Executor, path, user, group string) error {
+	return chownAll(e.SudoCommand, path, user, group)
+}
+
+func chownAll(run runFunc, path, user, group string) error {
+	cmd := gocmd.Command{Name: "chown", Args: []string{"-R", user + ":" + group, "--", path}}
+	if result, err := run(cmd); err != nil {
+		return fmt.Errorf("修改数据目录所属用户失败: %v, 标准错误输出: %s", err, result.Stderr)
 	}
 	return nil
 }
 
+// Chown 以当前用户在本机执行 chown, 不经过 sudo
 func Chown(path, user, group string) error {
-	cmd := fmt.Sprintf("chown %s:%s %s", user, group, path)
-	sh := gocmd.Shell{}
-	if _, stderr, err := sh.Run(cmd); err != nil {
-		return fmt.Errorf("修改数据目录所属用户失败: %v, 标准错误输出: %s", err, stderr)
+	return chown(executor.NewLocal().RunCommand, path, user, group)
+}
+
+// ChownOn 在指定的机器上以 sudo 修改 path 的所属用户和组
+func ChownOn(e executor.Executor, path, user, group string) error {
+	return chown(e.SudoCommand, path, user, group)
+}
+
+func chown(run runFunc, path, user, group string) error {
+	cmd := gocmd.Command{Name: "chown", Args: []string{user + ":" + group, "--", path}}
+	if result, err := run(cmd); err != nil {
+		return fmt.Errorf("修改数据目录所属用户失败: %v, 标准错误输出: %s", err, result.Stderr)
 	}
 	return nil
 }
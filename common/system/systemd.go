@@ -7,33 +7,67 @@ package system
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/lsne/goutils/utils/executor"
 	"github.com/lsne/goutils/utils/gocmd"
 )
 
+// runFunc 执行命令的方式, 本机的旧函数以当前用户直接执行(Executor.RunCommand), *On 函数以 sudo 执行(Executor.SudoCommand)
+type runFunc func(c gocmd.Command) (*gocmd.Result, error)
+
+// SystemdDaemonReload 以当前用户在本机执行, 不经过 sudo
 func SystemdDaemonReload() error {
-	cmd := "systemctl daemon-reload"
-	sh := gocmd.Shell{}
-	if stdout, stderr, err := sh.Run(cmd); err != nil {
-		return fmt.Errorf("daemon-reload失败: %v, 标准输出: %s, 标准错误: %s", err, stdout, stderr)
+	return systemdDaemonReload(executor.NewLocal().RunCommand)
+}
+
+// SystemdDaemonReloadOn 在指定的机器上以 sudo 执行 systemctl daemon-reload
+func SystemdDaemonReloadOn(e executor.Executor) error {
+	return systemdDaemonReload(e.SudoCommand)
+}
+
+func systemdDaemonReload(run runFunc) error {
+	cmd := gocmd.Command{Name: "systemctl", Args: []string{"daemon-reload"}}
+	if result, err := run(cmd); err != nil {
+		return fmt.Errorf("daemon-reload失败: %v, 标准输出: %s, 标准错误: %s", err, result.Stdout, result.Stderr)
 	}
 	return nil
 }
 
+// SystemCtl 以当前用户在本机执行, 不经过 sudo
 func SystemCtl(serviceName, action string) error {
-	cmd := fmt.Sprintf("systemctl %s %s", action, serviceName)
-	sh := gocmd.Shell{Timeout: 300}
-	if stdout, stderr, err := sh.Run(cmd); err != nil {
-		return fmt.Errorf("执行(%s)失败: %v, 标准输出: %s, 标准错误: %s", cmd, err, stdout, stderr)
+	l := &executor.Local{Shell: gocmd.Shell{Timeout: 300}}
+	return systemCtl(l.RunCommand, serviceName, action)
+}
+
+// SystemCtlOn 在指定的机器上以 sudo 执行 systemctl <action> <serviceName>
+func SystemCtlOn(e executor.Executor, serviceName, action string) error {
+	return systemCtl(e.SudoCommand, serviceName, action)
+}
+
+func systemCtl(run runFunc, serviceName, action string) error {
+	cmd := gocmd.Command{Name: "systemctl", Args: []string{action, "--", serviceName}}
+	if result, err := run(cmd); err != nil {
+		return fmt.Errorf("执行(%s)失败: %v, 标准输出: %s, 标准错误: %s", cmd, err, result.Stdout, result.Stderr)
 	}
 	return nil
 }
 
+// SystemResourceLimit 以当前用户在本机执行, 不经过 sudo
 func SystemResourceLimit(serviceName, limit string) error {
-	cmd := fmt.Sprintf("systemctl set-property %s %s", serviceName, limit)
-	sh := gocmd.Shell{}
-	if stdout, stderr, err := sh.Run(cmd); err != nil {
-		return fmt.Errorf("执行(%s)失败: %v, 标准输出: %s, 标准错误: %s", cmd, err, stdout, stderr)
+	return systemResourceLimit(executor.NewLocal().RunCommand, serviceName, limit)
+}
+
+// SystemResourceLimitOn 在指定的机器上以 sudo 执行 systemctl set-property <serviceName> <limit>
+// limit 可以包含多个以空白分隔的属性, 如 "CPUQuota=200% MemoryMax=4G"
+func SystemResourceLimitOn(e executor.Executor, serviceName, limit string) error {
+	return systemResourceLimit(e.SudoCommand, serviceName, limit)
+}
+
+func systemResourceLimit(run runFunc, serviceName, limit string) error {
+	cmd := gocmd.Command{Name: "systemctl", Args: append([]string{"set-property", "--", serviceName}, strings.Fields(limit)...)}
+	if result, err := run(cmd); err != nil {
+		return fmt.Errorf("执行(%s)失败: %v, 标准输出: %s, 标准错误: %s", cmd, err, result.Stdout, result.Stderr)
 	}
 	return nil
 }
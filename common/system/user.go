@@ -9,7 +9,9 @@ import (
 	"fmt"
 	"os/user"
 	"regexp"
+	"strings"
 
+	"github.com/lsne/goutils/utils/executor"
 	"github.com/lsne/goutils/utils/gocmd"
 	"github.com/lsne/goutils/utils/logger"
 )
@@ -24,14 +26,12 @@ func IsValidName(s string) bool {
 }
 
 // 如果用户已经存在,则返回真正的所属组名
+// 通过 user.Lookup 查询本机的用户, 以当前用户直接执行 groupadd 和 useradd, 不经过 sudo
 func CreateUser(username, groupName string) (string, string, error) {
 	logger.Infof("创建 linux 系统用户: %s", username)
 
-	if !IsValidName(username) {
-		return "", "", fmt.Errorf("invalid username: %s", username)
-	}
-	if !IsValidName(groupName) {
-		return "", "", fmt.Errorf("invalid group name: %s", groupName)
+	if err := checkUserName(username, groupName); err != nil {
+		return "", "", err
 	}
 
 	u, err := user.Lookup(username)
@@ -39,18 +39,47 @@ func CreateUser(username, groupName string) (string, string, error) {
 		g, _ := user.LookupGroupId(u.Gid)
 		return username, g.Name, nil
 	}
+	return createUser(executor.NewLocal().RunCommand, username, groupName)
+}
+
+// CreateUserOn 在指定的机器上创建用户, 如果用户已经存在,则返回真正的所属组名
+// 通过 id -gn 查询用户是否存在, 以 sudo 执行 groupadd 和 useradd
+func CreateUserOn(e executor.Executor, username, groupName string) (string, string, error) {
+	logger.Infof("在机器 %s 上创建 linux 系统用户: %s", e.Host(), username)
+
+	if err := checkUserName(username, groupName); err != nil {
+		return "", "", err
+	}
+
+	// 如果用户已经存在,则返回真正的所属组名
+	if result, err := e.RunCommand(gocmd.Command{Name: "id", Args: []string{"-gn", "--", username}, ReadOnly: true}); err == nil {
+		return username, strings.TrimSpace(string(result.Stdout)), nil
+	}
+	return createUser(e.SudoCommand, username, groupName)
+}
+
+func checkUserName(username, groupName string) error {
+	if !IsValidName(username) {
+		return fmt.Errorf("invalid username: %s", username)
+	}
+	if !IsValidName(groupName) {
+		return fmt.Errorf("invalid group name: %s", groupName)
+	}
+	return nil
+}
+
+func createUser(run runFunc, username, groupName string) (string, string, error) {
 	// groupadd -f <group-name>
-	groupAdd := fmt.Sprintf("%s -f %s", GroupAddCmd, groupName)
+	groupAdd := gocmd.Command{Name: GroupAddCmd, Args: []string{"-f", "--", groupName}}
 
 	// useradd -g <group-name> <user-name>
-	userAdd := fmt.Sprintf("%s -g %s %s", UserAddCmd, groupName, username)
+	userAdd := gocmd.Command{Name: UserAddCmd, Args: []string{"-g", groupName, "--", username}}
 
-	sh := gocmd.Shell{}
-	if _, stderr, err := sh.Run(groupAdd); err != nil {
-		return "", "", fmt.Errorf("创建用户组(%s)失败: %v, 标准错误输出: %s", groupName, err, stderr)
+	if result, err := run(groupAdd); err != nil {
+		return "", "", fmt.Errorf("创建用户组(%s)失败: %v, 标准错误输出: %s", groupName, err, result.Stderr)
 	}
-	if _, stderr, err := sh.Run(userAdd); err != nil {
-		return "", "", fmt.Errorf("创建用户(%s)失败: %v, 标准错误输出: %s", username, err, stderr)
+	if result, err := run(userAdd); err != nil {
+		return "", "", fmt.Errorf("创建用户(%s)失败: %v, 标准错误输出: %s", username, err, result.Stderr)
 	}
 	return username, groupName, nil
 }
@@ -6,12 +6,15 @@
 package systemd
 
 import (
+	"bytes"
 	"fmt"
+	"path"
 	"path/filepath"
 	"time"
 
 	"github.com/lsne/goutils/common/system"
 	"github.com/lsne/goutils/environment"
+	"github.com/lsne/goutils/utils/executor"
 	"github.com/lsne/goutils/utils/fileutil"
 
 	"gopkg.in/ini.v1"
@@ -35,9 +38,17 @@ type SystemdService struct {
 	WorkingDir  string
 	ExtraEnvs   []string
 	service     *ini.File
+	exec        executor.Executor // 为 nil 时在本机以当前用户直接操作, 不经过 sudo
 }
 
+// NewSystemdService 在本机管理 systemd 服务, 以当前用户直接写入 service 文件、执行 systemctl, 不经过 sudo
 func NewSystemdService(name, tmplfile string) (*SystemdService, error) {
+	return NewSystemdServiceOn(nil, name, tmplfile)
+}
+
+// NewSystemdServiceOn 在指定的机器上管理 systemd 服务, 模板文件从本地程序目录加载
+// 修改 service 文件和执行 systemctl 使用 e 的 sudo 权限(见 executor.Executor), e 为 nil 时同 NewSystemdService
+func NewSystemdServiceOn(e executor.Executor, name, tmplfile string) (*SystemdService, error) {
 	tmpl := filepath.Join(environment.GlobalEnv().ProgramPath, SystemdTemplatePath, tmplfile)
 	cfg, err := ini.LoadSources(ini.LoadOptions{
 		AllowShadows:             true,
@@ -46,7 +57,7 @@ func NewSystemdService(name, tmplfile string) (*SystemdService, error) {
 	if err != nil {
 		return &SystemdService{}, fmt.Errorf("加载ini文件(%s)失败: %v", tmpl, err)
 	}
-	return &SystemdService{name: name, template: tmplfile, servicePath: filepath.Join(SystemdPath, name), ExtraEnvs: make([]string, 0), service: cfg}, err
+	return &SystemdService{name: name, template: tmplfile, servicePath: path.Join(SystemdPath, name), ExtraEnvs: make([]string, 0), service: cfg, exec: e}, err
 }
 
 func (s *SystemdService) FormatBody() error {
@@ -97,7 +108,18 @@ func (s *SystemdService) Save() error {
 	if err := s.FormatBody(); err != nil {
 		return err
 	}
-	if err := s.service.SaveTo(s.servicePath); err != nil {
+	if s.exec == nil {
+		if err := s.service.SaveTo(s.servicePath); err != nil {
+			return err
+		}
+		return s.DaemonReload()
+	}
+
+	var buf bytes.Buffer
+	if _, err := s.service.WriteTo(&buf); err != nil {
+		return err
+	}
+	if err := s.exec.WriteFile(s.servicePath, buf.Bytes(), 0644); err != nil {
 		return err
 	}
 	return s.DaemonReload()
@@ -107,30 +129,50 @@ func (s *SystemdService) Remove() error {
 	if !s.IsExists() {
 		return nil
 	}
-	if err := fileutil.MoveToBackup(s.servicePath); err != nil {
+	var err error
+	if s.exec == nil {
+		err = fileutil.MoveToBackup(s.servicePath)
+	} else {
+		err = s.exec.Rename(s.servicePath, s.servicePath+fileutil.BackupSuffix())
+	}
+	if err != nil {
 		return err
 	}
 	return s.DaemonReload()
 }
 
 func (s *SystemdService) IsExists() bool {
-	return fileutil.IsExists(s.servicePath)
+	if s.exec == nil {
+		return fileutil.IsExists(s.servicePath)
+	}
+	return executor.IsExists(s.exec, s.servicePath)
 }
 
 func (s *SystemdService) DaemonReload() error {
-	return system.SystemdDaemonReload()
+	if s.exec == nil {
+		return system.SystemdDaemonReload()
+	}
+	return system.SystemdDaemonReloadOn(s.exec)
+}
+
+// systemCtl 本机以当前用户执行, 指定的机器上以 sudo 执行
+func (s *SystemdService) systemCtl(action string) error {
+	if s.exec == nil {
+		return system.SystemCtl(s.name, action)
+	}
+	return system.SystemCtlOn(s.exec, s.name, action)
 }
 
 func (s *SystemdService) Enable() error {
-	return system.SystemCtl(s.name, "enable")
+	return s.systemCtl("enable")
 }
 
 func (s *SystemdService) Disable() error {
-	return system.SystemCtl(s.name, "disable")
+	return s.systemCtl("disable")
 }
 
 func (s *SystemdService) Start() error {
-	if err := system.SystemCtl(s.name, "start"); err != nil {
+	if err := s.systemCtl("start"); err != nil {
 		return err
 	}
 	// 等待 5 秒, 防止进程还未就绪
@@ -139,7 +181,7 @@ func (s *SystemdService) Start() error {
 }
 
 func (s *SystemdService) Stop() error {
-	if err := system.SystemCtl(s.name, "stop"); err != nil {
+	if err := s.systemCtl("stop"); err != nil {
 		return fmt.Errorf("停止 systemd 服务(%s)失败： %w", s.name, err)
 	}
 	// 等待 5 秒, 防止进程还未就绪
@@ -162,5 +204,8 @@ func (s *SystemdService) DisableAndStop() error {
 }
 
 func (s *SystemdService) ResourceLimit(limit string) error {
-	return system.SystemResourceLimit(s.name, limit)
+	if s.exec == nil {
+		return system.SystemResourceLimit(s.name, limit)
+	}
+	return system.SystemResourceLimitOn(s.exec, s.name, limit)
 }
@@ -7,11 +7,16 @@ package systemd
 
 import (
 	"fmt"
-	"path/filepath"
+	"path"
 
-	"github.com/lsne/goutils/utils/fileutil"
+	"github.com/lsne/goutils/utils/executor"
 )
 
 func ServiceFileExists(name string) bool {
-	return fileutil.IsExists(filepath.Join(SystemdPath, fmt.Sprintf("%s.service", name)))
+	return ServiceFileExistsOn(executor.NewLocal(), name)
+}
+
+// ServiceFileExistsOn 判断指定机器上是否存在 <name>.service 文件
+func ServiceFileExistsOn(e executor.Executor, name string) bool {
+	return executor.IsExists(e, path.Join(SystemdPath, fmt.Sprintf("%s.service", name)))
 }
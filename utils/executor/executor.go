@@ -0,0 +1,103 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 15:30:12
+ */
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/lsne/goutils/utils/gocmd"
+	"github.com/lsne/goutils/utils/strutil"
+)
+
+// Executor 在本地或远程机器上执行命令、操作文件
+// Local 基于 gocmd.Shell 和本地文件系统实现, Remote 基于 gossh.Connection 实现
+// 修改文件的方法(WriteFile, MkdirAll, Rename, Copy)与 Sudo 使用相同的权限, 以 root 执行:
+// 执行用户已经是 root 时直接修改, 否则先写入只有执行用户可读的临时文件, 再经过 sudo 复制到目标位置, 创建的文件属主为 root
+type Executor interface {
+	// Host 返回执行命令的机器, 本地为 gocmd.LocalHost
+	Host() string
+	Run(cmd string) (*gocmd.Result, error)
+	Sudo(cmd string) (*gocmd.Result, error)
+	// RunCommand 和 SudoCommand 不经过 shell 解析参数, 用户输入的值应当通过这两个方法传入
+	RunCommand(c gocmd.Command) (*gocmd.Result, error)
+	SudoCommand(c gocmd.Command) (*gocmd.Result, error)
+	Stat(path string) (os.FileInfo, error)
+	ReadDir(path string) ([]os.FileInfo, error)
+	ReadFile(path string) ([]byte, error)
+	WriteFile(path string, data []byte, perm os.FileMode) error
+	MkdirAll(path string, perm os.FileMode) error
+	Rename(oldpath, newpath string) error
+	// Copy 将本地文件或目录复制到执行机器的 target, 规则同 gossh.Connection.Scp
+	Copy(source, target string) error
+}
+
+// 判断文件是否存在
+func IsExists(e Executor, path string) bool {
+	_, err := e.Stat(path)
+	return err == nil
+}
+
+// 判断所给路径是否为目录, 是否为文件可以用 !IsDir
+func IsDir(e Executor, path string) bool {
+	s, err := e.Stat(path)
+	if err != nil {
+		return false
+	}
+	return s.IsDir()
+}
+
+// 目录是否为空
+func IsEmpty(e Executor, path string) (bool, error) {
+	fs, err := e.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+	return len(fs) == 0, nil
+}
+
+// 验证数据目录, 存在看是否为空
+func IsDirEmptyOrNotExists(e Executor, dir string) error {
+	if !IsExists(e, dir) {
+		return nil
+	}
+
+	if !IsDir(e, dir) {
+		return fmt.Errorf("在机器: %s 上, 指定的路径(%s)不是目录", e.Host(), dir)
+	}
+
+	empty, err := IsEmpty(e, dir)
+	if err != nil {
+		return err
+	}
+	if !empty {
+		return fmt.Errorf("在机器: %s 上, 数据目录(%s)不为空", e.Host(), dir)
+	}
+	return nil
+}
+
+// ClearDir 删除目录下的所有内容, 不允许清空系统目录
+func ClearDir(e Executor, dir string) error {
+	if dir == "" {
+		return nil
+	}
+	if slices.Contains(gocmd.SystemDirs, strings.TrimSuffix(strings.TrimSpace(dir), "/")) {
+		return fmt.Errorf("目录(%s)是系统目录， 不允许删除", dir)
+	}
+
+	if !IsDir(e, dir) {
+		return fmt.Errorf("在机器: %s 上, %s 不是一个目录", e.Host(), dir)
+	}
+
+	cmd := fmt.Sprintf("cd %s; rm -rf *", strutil.Quote(filepath.ToSlash(dir)))
+	if result, err := e.Run(cmd); err != nil {
+		return fmt.Errorf("在机器: %s 上, 执行(%s)失败: %v, 标准输出: %s, 标准错误: %s", e.Host(), cmd, err, result.Stdout, result.Stderr)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 15:42:50
+ */
+
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/lsne/goutils/utils/fileutil"
+	"github.com/lsne/goutils/utils/gocmd"
+	"github.com/lsne/goutils/utils/strutil"
+)
+
+var _ Executor = (*Local)(nil)
+
+// Local 在本地机器上执行命令、操作文件
+// 读取文件使用当前用户的权限; 修改文件与 Sudo 相同以 root 执行: 当前用户为 root 时直接修改, 否则经过 sudo
+type Local struct {
+	Shell gocmd.Shell
+}
+
+func NewLocal() *Local {
+	return &Local{}
+}
+
+func (l *Local) Host() string {
+	return gocmd.LocalHost
+}
+
+func (l *Local) Run(cmd string) (*gocmd.Result, error) {
+	return l.Shell.Exec(cmd)
+}
+
+// Sudo 当前已经是 root 用户, 且没有指定 sudo 用户时, 直接执行命令
+func (l *Local) Sudo(cmd string) (*gocmd.Result, error) {
+	if l.Shell.User == "" && os.Geteuid() == 0 {
+		return l.Shell.Exec(cmd)
+	}
+	return l.Shell.SudoExec(cmd)
+}
+
+func (l *Local) RunCommand(c gocmd.Command) (*gocmd.Result, error) {
+	return l.Shell.ExecCommand(context.Background(), c)
+}
+
+// SudoCommand 规则同 Sudo
+func (l *Local) SudoCommand(c gocmd.Command) (*gocmd.Result, error) {
+	if l.Shell.User == "" && os.Geteuid() == 0 {
+		return l.Shell.ExecCommand(context.Background(), c)
+	}
+	return l.Shell.SudoCommand(context.Background(), c)
+}
+
+func (l *Local) Stat(path string) (os.FileInfo, error) {
+	return os.Stat(path)
+}
+
+func (l *Local) ReadDir(path string) ([]os.FileInfo, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (l *Local) ReadFile(path string) ([]byte, error) {
+	return os.ReadFile(path)
+}
+
+// WriteFile 当前用户为 root 时直接写入, 否则先写入权限为 0600 的临时文件, 再以 sudo install 复制到 path. 文件属主为 root
+// 设置了 Shell.Audit 时以 "write <path>" 演练或记录, 以下修改文件的方法相同
+func (l *Local) WriteFile(path string, data []byte, perm os.FileMode) error {
+	if !isRoot() {
+		return l.sudoWriteFile(path, data, perm)
+	}
+	return l.Shell.Change("write "+strutil.Quote(path), func() error {
+		return os.WriteFile(path, data, perm)
+	})
+}
+
+func (l *Local) sudoWriteFile(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp("", ".executor-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("写入临时文件(%s)失败: %v", f.Name(), err)
+	}
+	return l.sudo(gocmd.Command{Name: "install", Args: []string{"-m", fmt.Sprintf("%#o", perm.Perm()), "--", f.Name(), path}})
+}
+
+// MkdirAll 当前用户不是 root 时以 sudo mkdir -p 创建, 属主为 root
+func (l *Local) MkdirAll(path string, perm os.FileMode) error {
+	if !isRoot() {
+		if err := l.sudo(gocmd.Command{Name: "mkdir", Args: []string{"-p", "--", path}}); err != nil {
+			return err
+		}
+		return l.sudo(gocmd.Command{Name: "chmod", Args: []string{fmt.Sprintf("%#o", perm.Perm()), "--", path}})
+	}
+	return l.Shell.Change(fmt.Sprintf("mkdir -p -m %#o %s", perm.Perm(), strutil.Quote(path)), func() error {
+		return os.MkdirAll(path, perm)
+	})
+}
+
+// Rename 当前用户不是 root 时以 sudo mv -fT 重命名
+func (l *Local) Rename(oldpath, newpath string) error {
+	if !isRoot() {
+		return l.sudo(gocmd.Command{Name: "mv", Args: []string{"-fT", "--", oldpath, newpath}})
+	}
+	return l.Shell.Change("mv "+strutil.Quote(oldpath)+" "+strutil.Quote(newpath), func() error {
+		return fileutil.Rename(oldpath, newpath)
+	})
+}
+
+// Copy 复制本地文件或目录, 规则同 gossh.Connection.Scp. 当前用户不是 root 时以 sudo cp -r 复制
+func (l *Local) Copy(source, target string) error {
+	if !isRoot() {
+		if !fileutil.IsExists(source) {
+			return fmt.Errorf("文件 %s 不存在", source)
+		}
+		return l.sudo(gocmd.Command{Name: "cp", Args: []string{"-r", "--", source, target}})
+	}
+	return l.Shell.Change("cp -r "+strutil.Quote(source)+" "+strutil.Quote(target), func() error {
+		return copyLocal(source, target)
+	})
+}
+
+// sudo 以 root 执行文件操作, 忽略 Shell.User: 临时文件只有当前用户和 root 可以读取
+func (l *Local) sudo(c gocmd.Command) error {
+	sh := l.Shell
+	sh.User = ""
+	if result, err := sh.SudoCommand(context.Background(), c); err != nil {
+		return fmt.Errorf("执行(%s)失败: %v, 标准输出: %s, 标准错误: %s", c.String(), err, result.Stdout, result.Stderr)
+	}
+	return nil
+}
+
+func isRoot() bool {
+	return os.Geteuid() == 0
+}
+
+func copyLocal(source, target string) error {
+	if !fileutil.IsExists(source) {
+		return fmt.Errorf("文件 %s 不存在", source)
+	}
+
+	if !fileutil.IsDir(source) {
+		if fileutil.IsDir(target) {
+			target = filepath.Join(target, filepath.Base(source))
+		}
+		return fileutil.CopyFile(source, target)
+	}
+
+	if !fileutil.IsExists(target) {
+		return fileutil.CopyDir(source, target)
+	}
+
+	if !fileutil.IsDir(target) {
+		return fmt.Errorf("已经存在同名文件: %s", target)
+	}
+	return fileutil.CopyDir(source, filepath.Join(target, filepath.Base(source)))
+}
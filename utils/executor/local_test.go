@@ -0,0 +1,128 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-19 16:05:22
+ */
+
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/lsne/goutils/utils/gocmd"
+)
+
+func TestLocalDryRun(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "my.cnf")
+	if err := os.WriteFile(file, []byte("a=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	l := NewLocal()
+	l.Shell.Audit = &gocmd.Audit{DryRun: true}
+	for name, change := range map[string]func() error{
+		"WriteFile": func() error { return l.WriteFile(file, []byte("a=2\n"), 0644) },
+		"MkdirAll":  func() error { return l.MkdirAll(filepath.Join(dir, "data"), 0755) },
+		"Rename":    func() error { return l.Rename(file, file+".bak") },
+		"Copy":      func() error { return l.Copy(file, filepath.Join(dir, "copy.cnf")) },
+	} {
+		if err := change(); err != nil {
+			t.Errorf("%s: 演练时应返回 nil, 实际: %v", name, err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); len(entries) != 1 || string(data) != "a=1\n" {
+		t.Errorf("演练时不应修改文件, 目录下有 %d 个文件, 内容: %q", len(entries), data)
+	}
+	// 当前用户不是 root 时经过 sudo, 一个操作可能记录多条命令
+	if records := l.Shell.Audit.Records(); len(records) < 4 {
+		t.Errorf("每个操作都应记录: %+v", records)
+	}
+
+	// 只读命令在演练时仍然执行
+	result, err := l.RunCommand(gocmd.Command{Name: "cat", Args: []string{file}, ReadOnly: true})
+	if err != nil || string(result.Stdout) != "a=1\n" {
+		t.Errorf("演练时只读命令应执行, 输出: %q, 错误: %v", result.Stdout, err)
+	}
+}
+
+func TestLocalRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	l := NewLocal()
+	if l.Host() != gocmd.LocalHost {
+		t.Errorf("Host 不正确: %s", l.Host())
+	}
+	if result, err := l.Run("echo out; exit 0"); err != nil || string(result.Stdout) != "out\n" {
+		t.Errorf("Run 输出不正确: %q, %v", result.Stdout, err)
+	}
+	result, err := l.RunCommand(gocmd.Command{Name: "echo", Args: []string{"a b", "$HOME"}})
+	if err != nil || string(result.Stdout) != "a b $HOME\n" {
+		t.Errorf("RunCommand 的参数应原样传递, 输出: %q, 错误: %v", result.Stdout, err)
+	}
+	result, err = l.RunCommand(gocmd.Command{Name: "cat", Stdin: strings.NewReader("in")})
+	if err != nil || string(result.Stdout) != "in" {
+		t.Errorf("RunCommand 应支持标准输入, 输出: %q, 错误: %v", result.Stdout, err)
+	}
+}
+
+// 当前用户为 root 时直接修改文件, 否则需要 sudo, 不在测试中执行
+func TestLocalFiles(t *testing.T) {
+	if runtime.GOOS == "windows" || os.Geteuid() != 0 {
+		t.Skip("需要在类 unix 系统上以 root 执行")
+	}
+
+	l := NewLocal()
+	dir := filepath.Join(t.TempDir(), "data", "conf")
+	if err := l.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if !IsDir(l, dir) {
+		t.Fatalf("MkdirAll 没有创建目录: %s", dir)
+	}
+
+	file := filepath.Join(dir, "my.cnf")
+	if err := l.WriteFile(file, []byte("a=1\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := l.ReadFile(file); err != nil || string(data) != "a=1\n" {
+		t.Fatalf("WriteFile 写入的内容不正确: %q, %v", data, err)
+	}
+	if err := l.Rename(file, file+".bak"); err != nil {
+		t.Fatal(err)
+	}
+	if IsExists(l, file) || !IsExists(l, file+".bak") {
+		t.Error("Rename 后文件位置不正确")
+	}
+
+	target := t.TempDir()
+	if err := l.Copy(filepath.Dir(dir), target); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(target, "data", "conf", "my.cnf.bak")); err != nil || string(data) != "a=1\n" {
+		t.Errorf("Copy 到已存在的目录时应复制到目录下: %q, %v", data, err)
+	}
+
+	if err := IsDirEmptyOrNotExists(l, dir); err == nil {
+		t.Error("非空目录应返回错误")
+	}
+	if err := ClearDir(l, dir); err != nil {
+		t.Fatal(err)
+	}
+	if empty, err := IsEmpty(l, dir); err != nil || !empty {
+		t.Errorf("ClearDir 后目录应为空: %v, %v", empty, err)
+	}
+	if err := ClearDir(l, "/usr"); err == nil {
+		t.Error("不允许清空系统目录")
+	}
+}
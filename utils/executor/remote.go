@@ -0,0 +1,183 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 15:55:31
+ */
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/lsne/goutils/utils/gocmd"
+	"github.com/lsne/goutils/utils/gossh"
+	"github.com/lsne/goutils/utils/strutil"
+)
+
+var _ Executor = (*Remote)(nil)
+
+// Remote 通过 ssh 在远程机器上执行命令, 通过 sftp 操作文件
+// 读取文件使用 ssh 登录用户的权限; 修改文件与 Sudo 相同以 root 执行: 登录用户为 root 时直接通过 sftp 修改, 否则经过 sudo
+type Remote struct {
+	Conn    *gossh.Connection
+	Options []gossh.Option // 每次执行命令时使用的选项
+
+	rootMu sync.Mutex
+	root   *bool // 登录用户是否为 root 的缓存, 见 isRoot
+}
+
+// NewRemote 默认不在终端显示远程命令的输出, 与 Local 保持一致, 可以通过 gossh.WithHide(false) 修改
+func NewRemote(conn *gossh.Connection, opts ...gossh.Option) *Remote {
+	return &Remote{Conn: conn, Options: append([]gossh.Option{gossh.WithHide(true)}, opts...)}
+}
+
+func (r *Remote) Host() string {
+	return r.Conn.Host
+}
+
+func (r *Remote) Run(cmd string) (*gocmd.Result, error) {
+	return r.Conn.Exec(cmd, r.Options...)
+}
+
+func (r *Remote) Sudo(cmd string) (*gocmd.Result, error) {
+	return r.Conn.SudoExec(cmd, r.Options...)
+}
+
+// RunCommand 参数经过转义后在远程机器的 shell 中执行, 不支持 Stdin
+func (r *Remote) RunCommand(c gocmd.Command) (*gocmd.Result, error) {
+	if err := r.checkCommand(c); err != nil {
+		return &gocmd.Result{Host: r.Host(), Cmd: c.String()}, err
+	}
+	return r.Conn.Exec(c.String(), r.options(c)...)
+}
+
+// SudoCommand 参数经过转义后以 sudo 在远程机器的 shell 中执行, 不支持 Stdin
+func (r *Remote) SudoCommand(c gocmd.Command) (*gocmd.Result, error) {
+	if err := r.checkCommand(c); err != nil {
+		return &gocmd.Result{Host: r.Host(), Cmd: c.String()}, err
+	}
+	return r.Conn.SudoExec(c.String(), r.options(c)...)
+}
+
+// options 只读命令演练时仍然执行, 见 gocmd.Command.ReadOnly
+func (r *Remote) options(c gocmd.Command) []gossh.Option {
+	if !c.ReadOnly {
+		return r.Options
+	}
+	return append(slices.Clip(r.Options), gossh.WithReadOnly(true))
+}
+
+func (r *Remote) checkCommand(c gocmd.Command) error {
+	if c.Stdin != nil {
+		return fmt.Errorf("在机器: %s 上, 执行(%s)失败: 远程执行不支持标准输入", r.Host(), c.String())
+	}
+	return nil
+}
+
+func (r *Remote) Stat(path string) (os.FileInfo, error) {
+	return r.Conn.Stat(path)
+}
+
+func (r *Remote) ReadDir(path string) ([]os.FileInfo, error) {
+	return r.Conn.ReadDir(path)
+}
+
+func (r *Remote) ReadFile(path string) ([]byte, error) {
+	return r.Conn.ReadFile(path)
+}
+
+// WriteFile 登录用户为 root 时通过 sftp 直接写入, 否则先通过 sftp 写入 /tmp 下权限为 0600 的临时文件,
+// 再以 sudo install 复制到 path. 文件属主为 root
+func (r *Remote) WriteFile(path string, data []byte, perm os.FileMode) error {
+	if r.isRoot() {
+		return r.Conn.WriteFile(path, data, perm)
+	}
+
+	tmp := remoteTempPath()
+	if err := r.Conn.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	defer r.Conn.Remove(tmp)
+
+	return r.sudo(gocmd.Command{Name: "install", Args: []string{"-m", fmt.Sprintf("%#o", perm.Perm()), "--", tmp, path}})
+}
+
+// MkdirAll 登录用户不是 root 时以 sudo mkdir -p 创建, 属主为 root
+func (r *Remote) MkdirAll(path string, perm os.FileMode) error {
+	if r.isRoot() {
+		if err := r.Conn.MkdirAll(path); err != nil {
+			return err
+		}
+		return r.Conn.Chmod(path, perm)
+	}
+
+	if err := r.sudo(gocmd.Command{Name: "mkdir", Args: []string{"-p", "--", path}}); err != nil {
+		return err
+	}
+	return r.sudo(gocmd.Command{Name: "chmod", Args: []string{fmt.Sprintf("%#o", perm.Perm()), "--", path}})
+}
+
+// Rename 登录用户不是 root 时以 sudo mv -fT 重命名, newpath 已存在时覆盖
+func (r *Remote) Rename(oldpath, newpath string) error {
+	if r.isRoot() {
+		return r.Conn.PosixRename(oldpath, newpath)
+	}
+	return r.sudo(gocmd.Command{Name: "mv", Args: []string{"-fT", "--", oldpath, newpath}})
+}
+
+// Copy 登录用户不是 root 时先上传到 /tmp 下的临时目录, 再以 sudo cp -r 复制到 target, 规则同 gossh.Connection.Scp
+func (r *Remote) Copy(source, target string) error {
+	if r.isRoot() {
+		return r.Conn.Scp(source, target)
+	}
+
+	tmp := remoteTempPath()
+	if err := r.Conn.Mkdir(tmp); err != nil {
+		return err
+	}
+	defer r.Conn.RemoveAll(tmp)
+	if err := r.Conn.Chmod(tmp, 0700); err != nil {
+		return err
+	}
+	if err := r.Conn.Scp(source, tmp); err != nil {
+		return err
+	}
+
+	return r.sudo(gocmd.Command{Name: "cp", Args: []string{"-r", "--", path.Join(tmp, filepath.Base(source)), target}})
+}
+
+// isRoot 以 id -u 判断登录用户是否为 root(uid 为 0 的用户不一定叫 root), 查询成功后缓存结果, 查询失败时按非 root 处理
+func (r *Remote) isRoot() bool {
+	r.rootMu.Lock()
+	defer r.rootMu.Unlock()
+	if r.root != nil {
+		return *r.root
+	}
+
+	result, err := r.Conn.Exec("id -u", gossh.WithHide(true), gossh.WithReadOnly(true), gossh.WithPty(false))
+	if err != nil {
+		return false
+	}
+	root := strings.TrimSpace(string(result.Stdout)) == "0"
+	r.root = &root
+	return root
+}
+
+// sudo 以 root 执行文件操作, 忽略 Options 中的 sudo 用户: 临时文件只有登录用户和 root 可以读取
+func (r *Remote) sudo(c gocmd.Command) error {
+	opts := append(slices.Clip(r.Options), gossh.WithSudoUser(""))
+	if result, err := r.Conn.SudoExec(c.String(), opts...); err != nil {
+		return fmt.Errorf("在机器: %s 上, 执行(%s)失败: %v, 标准输出: %s", r.Host(), c.String(), err, result.Stdout)
+	}
+	return nil
+}
+
+// remoteTempPath 远程机器上的临时文件路径
+func remoteTempPath() string {
+	return "/tmp/.executor-" + strutil.GenerateString(12)
+}
@@ -0,0 +1,117 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-19 16:40:18
+ */
+
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lsne/goutils/utils/gocmd"
+	"github.com/lsne/goutils/utils/gossh"
+	"github.com/lsne/goutils/utils/gossh/sshtest"
+)
+
+func newTestRemote(t *testing.T) (*Remote, *sshtest.Server) {
+	t.Helper()
+
+	s := sshtest.NewServer(t)
+	conn, err := gossh.NewConnection(s.Host, s.Port, s.User, s.Password, "", 5,
+		gossh.WithKnownHostsFile(s.KnownHostsFile), gossh.WithHostKeyPolicy(gossh.HostKeyStrict), gossh.WithReuse(false))
+	if err != nil {
+		t.Fatalf("连接测试服务失败: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return NewRemote(conn, gossh.WithSudoPassword(s.Password)), s
+}
+
+func TestRemoteRun(t *testing.T) {
+	r, s := newTestRemote(t)
+
+	if r.Host() != s.Host {
+		t.Errorf("Host 不正确: %s", r.Host())
+	}
+	result, err := r.RunCommand(gocmd.Command{Name: "echo", Args: []string{"a b", "$HOME"}})
+	if err != nil || string(result.Stdout) != "a b $HOME\n" {
+		t.Errorf("RunCommand 的参数应原样传递, 输出: %q, 错误: %v", result.Stdout, err)
+	}
+	if result, err := r.Sudo("echo ok"); err != nil || !strings.HasSuffix(string(result.Stdout), "ok\n") {
+		t.Errorf("Sudo 执行失败, 输出: %q, 错误: %v", result.Stdout, err)
+	}
+	if _, err := r.RunCommand(gocmd.Command{Name: "cat", Stdin: strings.NewReader("x")}); err == nil {
+		t.Error("远程执行不支持标准输入, 应返回错误")
+	}
+}
+
+// 登录用户不是 root 时, 修改文件经过 sudo
+func TestRemoteFiles(t *testing.T) {
+	r, s := newTestRemote(t)
+
+	// 测试服务以当前用户执行命令, 以 id -u 判断是否为 root
+	if r.isRoot() != (os.Geteuid() == 0) {
+		t.Fatalf("isRoot 应与 id -u 一致, 实际: %v", r.isRoot())
+	}
+	notRoot := false
+	r.root = &notRoot
+
+	dir := filepath.Join(s.Dir, "data", "conf")
+	if err := r.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := r.Stat(dir); err != nil || !info.IsDir() || info.Mode().Perm() != 0750 {
+		t.Fatalf("MkdirAll 创建的目录不正确: %v, %v", info, err)
+	}
+
+	file := filepath.Join(dir, "my.cnf")
+	if err := r.WriteFile(file, []byte("a=1\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := r.ReadFile(file); err != nil || string(data) != "a=1\n" {
+		t.Fatalf("WriteFile 写入的内容不正确: %q, %v", data, err)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("WriteFile 设置的权限不正确: %v, %v", info, err)
+	}
+
+	if err := r.Rename(file, file+".bak"); err != nil {
+		t.Fatal(err)
+	}
+	if IsExists(r, file) || !IsExists(r, file+".bak") {
+		t.Error("Rename 后文件位置不正确")
+	}
+
+	local := filepath.Join(t.TempDir(), "local.cnf")
+	if err := os.WriteFile(local, []byte("b=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Copy(local, dir); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "local.cnf")); err != nil || string(data) != "b=1\n" {
+		t.Errorf("Copy 到已存在的目录时应复制到目录下: %q, %v", data, err)
+	}
+
+	// 临时文件都已删除
+	if matches, _ := filepath.Glob("/tmp/.executor-*"); len(matches) > 0 {
+		t.Errorf("临时文件没有删除: %v", matches)
+	}
+
+	if err := IsDirEmptyOrNotExists(r, filepath.Join(s.Dir, "none")); err != nil {
+		t.Errorf("不存在的目录应返回 nil: %v", err)
+	}
+	if err := IsDirEmptyOrNotExists(r, dir); err == nil {
+		t.Error("非空目录应返回错误")
+	}
+	if err := ClearDir(r, dir); err != nil {
+		t.Fatal(err)
+	}
+	if empty, err := IsEmpty(r, dir); err != nil || !empty {
+		t.Errorf("ClearDir 后目录应为空: %v, %v", empty, err)
+	}
+}
@@ -131,22 +131,23 @@ func CopyDir(src, dst string) error {
 	})
 }
 
-func backupSuffix() string {
+// BackupSuffix 返回备份文件的后缀, 格式为 .bak.<年月日时分秒>
+func BackupSuffix() string {
 	return ".bak." + time.Now().Format("20060102150405")
 }
 
 func MoveToBackup(src string) error {
-	dst := filepath.Clean(src) + backupSuffix()
+	dst := filepath.Clean(src) + BackupSuffix()
 	return Rename(src, dst)
 }
 
 func BackupFile(src string) error {
-	dst := filepath.Clean(src) + backupSuffix()
+	dst := filepath.Clean(src) + BackupSuffix()
 	return CopyFile(src, dst)
 }
 
 func BackupDir(src string) error {
-	dst := filepath.Clean(src) + backupSuffix()
+	dst := filepath.Clean(src) + BackupSuffix()
 	return CopyDir(src, dst)
 }
 
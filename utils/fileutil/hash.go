@@ -23,6 +23,20 @@ func FileMD5(file string) (string, error) {
 	return checksum, nil
 }
 
+// 生成指定文件前 n 个字节的md5效验码, 文件不足 n 个字节时返回错误
+func FileHeadMD5(file string, n int64) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	m := md5.New()
+	if _, err := io.CopyN(m, f, n); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(m.Sum(nil)), nil
+}
+
 // 生成指定字符数组的md5效验码
 func BytesMD5(b []byte) string {
 	m := md5.New()
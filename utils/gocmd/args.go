@@ -0,0 +1,97 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 11:20:07
+ */
+
+package gocmd
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/lsne/goutils/utils/strutil"
+)
+
+// Command 不经过 shell 直接执行的命令, 参数原样传给程序, 不做任何 shell 解析(引号、$、;、| 等都没有特殊含义)
+// 用户输入的服务名、用户名、路径等应当作为 Args 传入, 而不是拼接到 Run 的命令字符串中
+type Command struct {
+	Name  string    // 程序名, 不包含路径分隔符时在 PATH 中查找
+	Args  []string  // 参数
+	Env   []string  // 追加的环境变量, 格式为 KEY=VALUE
+	Dir   string    // 工作目录, 为空时使用当前目录
+	Stdin io.Reader // 标准输入, 为 nil 时不输入
+
+	// 只读命令(如查询状态、计算 md5), 不修改系统. 设置了 Shell.Audit 时不演练也不记录, 演练模式下仍然执行并返回真实的输出
+	ReadOnly bool
+}
+
+// String 返回转义后的命令行, 可以安全地交给 shell 执行(如在远程机器上执行), 结果与直接执行相同
+func (c Command) String() string {
+	var b strings.Builder
+	if c.Dir != "" {
+		b.WriteString("cd " + strutil.Quote(c.Dir) + " && ")
+	}
+	if len(c.Env) > 0 {
+		b.WriteString("env")
+		for _, env := range c.Env {
+			b.WriteString(" " + strutil.Quote(env))
+		}
+		b.WriteString(" ")
+	}
+	b.WriteString(strutil.Quote(c.Name))
+	for _, arg := range c.Args {
+		b.WriteString(" " + strutil.Quote(arg))
+	}
+	return b.String()
+}
+
+// RunArgs 不经过 shell 执行 name args..., 返回标准输出和标准错误
+func (sh *Shell) RunArgs(name string, args ...string) ([]byte, []byte, error) {
+	result, err := sh.ExecCommand(context.Background(), Command{Name: name, Args: args})
+	return result.Stdout, result.Stderr, err
+}
+
+// SudoArgs 不经过 shell, 以 sudo 执行 name args...
+func (sh *Shell) SudoArgs(name string, args ...string) ([]byte, []byte, error) {
+	result, err := sh.SudoCommand(context.Background(), Command{Name: name, Args: args})
+	return result.Stdout, result.Stderr, err
+}
+
+// ExecCommand 不经过 shell 执行 c, 返回值和超时处理同 ExecContext
+func (sh *Shell) ExecCommand(ctx context.Context, c Command) (*Result, error) {
+	if sh.Locale != "" {
+		c.Env = append([]string{"LANG=" + sh.Locale}, c.Env...)
+	}
+	return sh.forCommand(c).exec(ctx, c.String(), c, sh.Watchers)
+}
+
+// SudoCommand 不经过 shell, 以 sudo 执行 c. Shell.User 不为空时以该用户执行
+// c.Env 通过 env 命令传给目标程序, 不受 sudo 的 env_reset 影响. 密码的处理和返回的错误同 SudoExecContext
+func (sh *Shell) SudoCommand(ctx context.Context, c Command) (*Result, error) {
+	if sh.Locale != "" {
+		c.Env = append([]string{"LANG=" + sh.Locale}, c.Env...)
+	}
+
+	args, watchers := sh.sudoArgs()
+	args = append(args, "--")
+	if len(c.Env) > 0 {
+		args = append(append(append(args, "env"), c.Env...), c.Name)
+	} else {
+		args = append(args, c.Name)
+	}
+
+	sudo := Command{Name: "sudo", Args: append(args, c.Args...), Dir: c.Dir, Stdin: c.Stdin}
+	result, err := sh.forCommand(c).exec(ctx, sudo.String(), sudo, watchers)
+	return result, SudoPasswordRequiredError(result, err)
+}
+
+// forCommand 只读命令不演练也不记录, 返回不设置 Audit 的副本
+func (sh *Shell) forCommand(c Command) *Shell {
+	if !c.ReadOnly || sh.Audit == nil {
+		return sh
+	}
+	probe := *sh
+	probe.Audit = nil
+	return &probe
+}
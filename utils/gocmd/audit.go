@@ -0,0 +1,169 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 15:10:44
+ */
+
+package gocmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Redacted 审计记录中替换密码的字符串
+const Redacted = "******"
+
+// AuditRecord 一条审计记录, 在审计日志中占一行 JSON
+type AuditRecord struct {
+	Time     time.Time `json:"time"`
+	Host     string    `json:"host"`
+	User     string    `json:"user"` // 执行命令的用户, 本地为当前用户, 远程为 ssh 登录用户
+	Command  string    `json:"command"`
+	DryRun   bool      `json:"dry_run,omitempty"`
+	ExitCode int       `json:"exit_code"`
+	Signal   string    `json:"signal,omitempty"`
+	Duration float64   `json:"duration"` // 耗时, 单位秒
+	Error    string    `json:"error,omitempty"`
+}
+
+// Audit 命令的演练(dry-run)和审计设置, 本地(Shell)和远程(gossh.Connection)执行使用相同的设置, 可以并发使用
+// DryRun 为 true 时只记录要执行的命令, 不执行, 返回空输出和退出码 0
+// 每执行(或演练)一条命令, 向 Writer 追加一条 AuditRecord, 记录中的 sudo 密码和 ssh 登录密码替换为 Redacted
+// 不经过命令的变更(写文件、创建目录等)通过 Change 以等价的命令记录; 只读命令(Command.ReadOnly)不演练也不记录
+type Audit struct {
+	DryRun bool
+	Writer io.Writer // 审计日志, 为 nil 时不写入
+
+	mu      sync.Mutex
+	closer  io.Closer
+	records []AuditRecord
+}
+
+// OpenAuditFile 以追加方式打开审计日志文件, 不存在时创建, 使用完后需要调用 Close
+func OpenAuditFile(path string, dryRun bool) (*Audit, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志(%s)失败: %v", path, err)
+	}
+	return &Audit{DryRun: dryRun, Writer: f, closer: f}, nil
+}
+
+// Close 关闭 OpenAuditFile 打开的文件
+func (a *Audit) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closer == nil {
+		return nil
+	}
+	err := a.closer.Close()
+	a.closer = nil
+	return err
+}
+
+// Records 返回演练模式下记录的所有命令, 非演练模式不在内存中保留记录
+func (a *Audit) Records() []AuditRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]AuditRecord(nil), a.records...)
+}
+
+// Record 追加一条记录, secrets 中的内容在 Command 和 Error 中替换为 Redacted
+func (a *Audit) Record(r AuditRecord, secrets ...string) error {
+	r.DryRun = a.DryRun
+	r.Command = redact(r.Command, secrets)
+	r.Error = redact(r.Error, secrets)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.DryRun {
+		a.records = append(a.records, r)
+	}
+	if a.Writer == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err = a.Writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("写入审计日志失败: %v", err)
+	}
+	return nil
+}
+
+// RecordResult 根据命令的执行结果追加一条记录, 写入失败且 err 为 nil 时返回写入的错误, 否则原样返回 err
+func (a *Audit) RecordResult(result *Result, user string, err error, secrets ...string) error {
+	r := AuditRecord{
+		Time:     result.StartTime,
+		Host:     result.Host,
+		User:     user,
+		Command:  result.Cmd,
+		ExitCode: result.ExitCode,
+		Signal:   result.Signal,
+		Duration: result.Duration().Seconds(),
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	if rerr := a.Record(r, secrets...); rerr != nil && err == nil {
+		return fmt.Errorf("在机器: %s 上, %v", result.Host, rerr)
+	}
+	return err
+}
+
+// Change 所有不经过命令执行的变更(如通过 sftp 写文件、修改属主、删除)都经过此方法演练或记录, cmd 为记录的等价命令
+// a 为 nil 时直接执行 fn; 演练时不执行 fn, 只记录并返回 nil; 否则执行 fn 并记录结果, 返回 fn 的错误
+func (a *Audit) Change(host string, user string, cmd string, fn func() error, secrets ...string) error {
+	if a == nil {
+		return fn()
+	}
+
+	result := DryRunResult(host, cmd)
+	if a.DryRun {
+		return a.RecordResult(result, user, nil, secrets...)
+	}
+
+	err := fn()
+	result.EndTime = time.Now()
+	if err != nil {
+		result.ExitCode = -1
+	}
+	return a.RecordResult(result, user, err, secrets...)
+}
+
+// Change 本地不经过命令执行的变更(如写文件、创建目录)通过此方法演练或记录, 规则同 Audit.Change
+func (sh *Shell) Change(cmd string, fn func() error) error {
+	return sh.Audit.Change(LocalHost, currentUser(), cmd, fn, sh.SudoPassword)
+}
+
+// DryRunResult 演练时返回的结果: 不执行, 输出为空, 退出码为 0
+func DryRunResult(host string, cmd string) *Result {
+	now := time.Now()
+	return &Result{Host: host, Cmd: cmd, StartTime: now, EndTime: now}
+}
+
+func redact(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
+
+// currentUser 本地执行命令的用户
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
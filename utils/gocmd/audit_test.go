@@ -0,0 +1,112 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 15:42:09
+ */
+
+package gocmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestShellAudit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	// 演练时不执行命令
+	file := filepath.Join(t.TempDir(), "created")
+	sh := Shell{Audit: &Audit{DryRun: true}, SudoPassword: "secret"}
+	if _, err := sh.Exec("touch " + file + " # secret"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("演练时不应执行命令: %v", err)
+	}
+	if records := sh.Audit.Records(); len(records) != 1 || !records[0].DryRun || strings.Contains(records[0].Command, "secret") {
+		t.Errorf("演练记录不正确: %+v", records)
+	}
+
+	var log bytes.Buffer
+	sh = Shell{Audit: &Audit{Writer: &log}}
+	_, _ = sh.Exec("true")
+	var exitErr *ExitError
+	if _, err := sh.Exec("exit 3"); !errors.As(err, &exitErr) {
+		t.Fatalf("应返回 ExitError, 实际: %v", err)
+	}
+
+	var records []AuditRecord
+	scanner := bufio.NewScanner(&log)
+	for scanner.Scan() {
+		var r AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("审计日志格式不正确: %s", scanner.Text())
+		}
+		records = append(records, r)
+	}
+	if len(records) != 2 || records[0].ExitCode != 0 || records[1].ExitCode != 3 || records[1].Error == "" || records[1].Host != LocalHost || records[1].User == "" {
+		t.Errorf("审计记录不正确: %+v", records)
+	}
+}
+
+func TestAuditChange(t *testing.T) {
+	var called bool
+	change := func() error {
+		called = true
+		return errors.New("失败")
+	}
+
+	// Audit 为 nil 时直接执行
+	var a *Audit
+	if err := a.Change(LocalHost, "root", "rm -rf /data", change); err == nil || !called {
+		t.Errorf("Audit 为 nil 时应直接执行并返回错误, 实际: %v, %v", called, err)
+	}
+
+	// 演练时不执行, 只记录
+	called = false
+	a = &Audit{DryRun: true}
+	if err := a.Change(LocalHost, "root", "write /data/my.cnf secret", change, "secret"); err != nil || called {
+		t.Errorf("演练时不应执行, 实际: %v, %v", called, err)
+	}
+	if records := a.Records(); len(records) != 1 || records[0].Command != "write /data/my.cnf "+Redacted {
+		t.Errorf("演练记录不正确: %+v", records)
+	}
+
+	// 非演练时执行并记录结果
+	var log bytes.Buffer
+	a = &Audit{Writer: &log}
+	if err := a.Change(LocalHost, "root", "mkdir -p /data", change); err == nil || !called {
+		t.Errorf("应执行并返回错误, 实际: %v, %v", called, err)
+	}
+	var r AuditRecord
+	if err := json.Unmarshal(log.Bytes(), &r); err != nil || r.Command != "mkdir -p /data" || r.ExitCode != -1 || r.Error == "" {
+		t.Errorf("审计记录不正确: %s", log.String())
+	}
+}
+
+func TestShellReadOnly(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	// 演练时只读命令仍然执行, 不记录
+	sh := Shell{Audit: &Audit{DryRun: true}}
+	result, err := sh.ExecCommand(t.Context(), Command{Name: "echo", Args: []string{"probe"}, ReadOnly: true})
+	if err != nil || string(result.Stdout) != "probe\n" {
+		t.Fatalf("演练时只读命令应执行, 输出: %q, 错误: %v", result.Stdout, err)
+	}
+	if _, err := sh.ExecCommand(t.Context(), Command{Name: "echo", Args: []string{"change"}}); err != nil {
+		t.Fatal(err)
+	}
+	if records := sh.Audit.Records(); len(records) != 1 || !strings.Contains(records[0].Command, "change") {
+		t.Errorf("只应记录非只读命令: %+v", records)
+	}
+}
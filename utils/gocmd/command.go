@@ -8,87 +8,290 @@ package gocmd
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
+	"slices"
+	"syscall"
 	"time"
 
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
 )
 
+// DefaultKillGrace 超时或取消时, 发送 SIGTERM 后等待命令退出的默认秒数
+const DefaultKillGrace = 5
+
+// DefaultSudoPattern 通过 sudo -p 设置的默认密码提示, 用于匹配后应答 sudo 密码
+const DefaultSudoPattern = "[sudo] password: "
+
 // Shell execute the command at local host.
 type Shell struct {
-	Timeout int
-	User    string // sudo 用户名。 默认为空时, 执行sudo不传-u参数, 以默认root执行
-	Locale  string // the locale used when executing the command
+	Timeout   int
+	KillGrace int    // 超时或取消时先向进程组发送 SIGTERM, 等待 KillGrace 秒后仍未退出则发送 SIGKILL, 为 0 时使用 DefaultKillGrace
+	User      string // sudo 用户名。 默认为空时, 执行sudo不传-u参数, 以默认root执行
+	Locale    string // the locale used when executing the command
+	Output    Output // 输出的实时转发、截断和 ANSI 过滤设置, 默认只缓存到 Result
+
+	// sudo 密码, 执行 Sudo 时匹配到 SudoPattern 后输入. 为空时以 sudo -n 执行, 需要密码时返回 *PasswordRequiredError
+	SudoPassword string
+	SudoPattern  string       // sudo 密码提示, 为空时使用 DefaultSudoPattern
+	Watchers     []Watcher    // 扫描命令的输出并应答, 设置后不能再通过 Command.Stdin 输入
+	Audit        *Audit       // 演练和审计设置, 为 nil 时直接执行, 不记录
+	Retry        *RetryPolicy // 执行失败后的重试策略, 为 nil 时不重试
+}
+
+// WithRetry 返回使用重试策略 p 的 Shell 副本, 用于单次调用, 如 sh.WithRetry(p).Sudo("yum install -y lsof")
+func (sh *Shell) WithRetry(p RetryPolicy) *Shell {
+	c := *sh
+	c.Retry = &p
+	return &c
 }
 
 func (sh *Shell) Run(cmd string) ([]byte, []byte, error) {
+	return sh.RunContext(context.Background(), cmd)
+}
+
+// RunContext 与 Run 相同, ctx 被取消或超过 Timeout 时终止命令所在的整个进程组(包括子进程),
+// 返回 *TimeoutError, 其中包含已经捕获到的部分输出, 通过 TimeoutError.Timeout() 区分超时和取消
+func (sh *Shell) RunContext(ctx context.Context, cmd string) ([]byte, []byte, error) {
+	result, err := sh.ExecContext(ctx, cmd)
+	return result.Stdout, result.Stderr, err
+}
+
+// Exec 与 Run 相同, 返回包含退出码、输出、执行时间等信息的 Result
+// 退出码不为 0 时返回 *ExitError, 超时返回 *TimeoutError
+func (sh *Shell) Exec(cmd string) (*Result, error) {
+	return sh.ExecContext(context.Background(), cmd)
+}
+
+// ExecContext 与 RunContext 相同, 返回 Result
+func (sh *Shell) ExecContext(ctx context.Context, cmd string) (*Result, error) {
+	cmd = sh.script(cmd)
+	return sh.exec(ctx, cmd, Command{Name: "/bin/sh", Args: []string{"-c", cmd}}, sh.Watchers)
+}
+
+// script 在命令前加上 PATH 和 LANG 的设置
+func (sh *Shell) script(cmd string) string {
 	// set a basic PATH in case it's empty on login
 	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
 
 	if sh.Locale != "" {
 		cmd = fmt.Sprintf("export LANG=%s; %s", sh.Locale, cmd)
 	}
+	return cmd
+}
 
-	if sh.Timeout == 0 {
-		sh.Timeout = 60
-	}
+func (sh *Shell) Sudo(cmd string) ([]byte, []byte, error) {
+	return sh.SudoContext(context.Background(), cmd)
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(sh.Timeout)*time.Second)
-	defer cancel()
+// SudoContext 与 Sudo 相同, 取消和超时的处理方式见 RunContext
+func (sh *Shell) SudoContext(ctx context.Context, cmd string) ([]byte, []byte, error) {
+	result, err := sh.SudoExecContext(ctx, cmd)
+	return result.Stdout, result.Stderr, err
+}
 
-	command := exec.CommandContext(ctx, "/bin/sh", "-c", cmd)
+// SudoExec 与 Sudo 相同, 返回 Result
+func (sh *Shell) SudoExec(cmd string) (*Result, error) {
+	return sh.SudoExecContext(context.Background(), cmd)
+}
 
-	stdout := new(bytes.Buffer)
-	stderr := new(bytes.Buffer)
-	command.Stdout = stdout
-	command.Stderr = stderr
+// SudoExecContext 与 SudoContext 相同, 返回 Result
+// sudo 密码错误时返回 *SentinelError, 需要密码但没有设置 SudoPassword 时返回 *PasswordRequiredError
+func (sh *Shell) SudoExecContext(ctx context.Context, cmd string) (*Result, error) {
+	args, watchers := sh.sudoArgs()
+	// cmd 整体作为 bash -c 的一个参数转义, 其中的变量、引号和命令替换都由 sudo 之后的 bash 解析
+	sudo := Command{Name: "sudo", Args: append(args, "/bin/bash", "-c", "cd; "+cmd)}
+	cmd = sh.script(sudo.String())
 
-	err := command.Run()
+	result, err := sh.exec(ctx, cmd, Command{Name: "/bin/sh", Args: []string{"-c", cmd}}, watchers)
+	return result, SudoPasswordRequiredError(result, err)
+}
 
-	if err != nil {
-		return stdout.Bytes(), stderr.Bytes(), err
+// sudoArgs 返回 sudo 的参数和应答密码的 Watcher, 设置了 SudoPassword 时从标准输入读取密码, 否则以 -n 执行
+func (sh *Shell) sudoArgs() ([]string, []Watcher) {
+	watchers := sh.Watchers
+	var args []string
+	if sh.SudoPassword != "" {
+		pattern := sh.SudoPattern
+		if pattern == "" {
+			pattern = DefaultSudoPattern
+		}
+		args = []string{"-S", "-p", pattern}
+		// 密码错误时 sudo 会再次提示输入, 只应答一次, 并以 "Sorry, try again" 判定失败
+		watcher := Watcher{Pattern: pattern, Response: sh.SudoPassword, Sentinel: "Sorry, try again", Once: true, Stderr: true}
+		watchers = append(slices.Clip(watchers), watcher)
+	} else {
+		args = []string{"-n"}
 	}
 
-	return stdout.Bytes(), stderr.Bytes(), nil
-}
-
-func (sh *Shell) Sudo(cmd string) ([]byte, []byte, error) {
-	var sudoStr string
+	args = append(args, "-H")
 	if sh.User != "" {
-		sudoStr = " -u " + sh.User
+		args = append(args, "-u", sh.User)
 	}
-	cmd = fmt.Sprintf("sudo -S -H %s /bin/bash -c \"cd; %s\"", sudoStr, cmd)
-	return sh.Run(cmd)
+	return args, watchers
 }
 
+// WinRun 通过 cmd /c 执行命令, 没有设置 Output.Encoding 时按 GBK 转换输出
 func (sh *Shell) WinRun(cmd string) ([]byte, []byte, error) {
+	win := *sh
+	if win.Output.Encoding == EncodingUTF8 {
+		win.Output.Encoding = EncodingGBK
+	}
+	result, err := win.exec(context.Background(), cmd, Command{Name: "cmd", Args: []string{"/c", cmd}}, sh.Watchers)
+	return result.Stdout, result.Stderr, err
+}
+
+// exec 不经过 shell 执行 c, cmdline 仅用于记录到 Result.Cmd 和审计日志. 设置了 Retry 时按策略重试
+func (sh *Shell) exec(ctx context.Context, cmdline string, c Command, watchers []Watcher) (*Result, error) {
+	if sh.Retry == nil {
+		return sh.audit(ctx, cmdline, c, watchers)
+	}
+	if c.Stdin != nil && sh.Retry.MaxAttempts > 1 {
+		return &Result{Host: LocalHost, Cmd: cmdline, ExitCode: -1}, fmt.Errorf("执行(%s)失败: 指定了标准输入时不能重试", cmdline)
+	}
+	return sh.Retry.Do(ctx, func() (*Result, error) {
+		return sh.audit(ctx, cmdline, c, watchers)
+	})
+}
+
+// audit 设置了 Audit 时演练或记录命令
+func (sh *Shell) audit(ctx context.Context, cmdline string, c Command, watchers []Watcher) (*Result, error) {
+	if sh.Audit == nil {
+		return sh.run(ctx, cmdline, c, watchers)
+	}
+
+	if sh.Audit.DryRun {
+		result := DryRunResult(LocalHost, cmdline)
+		return result, sh.Audit.RecordResult(result, currentUser(), nil, sh.SudoPassword)
+	}
+
+	result, err := sh.run(ctx, cmdline, c, watchers)
+	return result, sh.Audit.RecordResult(result, currentUser(), err, sh.SudoPassword)
+}
+
+// run 执行命令, 有 Watcher 时通过管道向命令的标准输入写入应答, 匹配到 Sentinel 时终止命令并返回 *SentinelError
+func (sh *Shell) run(ctx context.Context, cmdline string, c Command, watchers []Watcher) (*Result, error) {
 	if sh.Timeout == 0 {
 		sh.Timeout = 60
 	}
+	grace := sh.KillGrace
+	if grace <= 0 {
+		grace = DefaultKillGrace
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(sh.Timeout)*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(sh.Timeout)*time.Second)
 	defer cancel()
 
-	command := exec.CommandContext(ctx, "cmd", "/c", cmd)
+	command := exec.Command(c.Name, c.Args...)
+	command.Dir = c.Dir
+	command.Stdin = c.Stdin
+	if len(c.Env) > 0 {
+		command.Env = append(os.Environ(), c.Env...)
+	}
+	setProcessGroup(command)
+	// 命令退出后, 脱离进程组的后台子进程可能仍然持有输出管道, 最多再等待 grace 秒
+	command.WaitDelay = time.Duration(grace) * time.Second
+
+	output := sh.Output
+	if output.Encoding == EncodingUTF8 {
+		output.Encoding = EncodingFromLocale(sh.Locale)
+	}
+
+	result := &Result{Host: LocalHost, Cmd: cmdline, ExitCode: -1, StartTime: time.Now()}
+	capture := output.NewCapture(LocalHost)
+	command.Stdout = capture.Stdout
+	command.Stderr = capture.Stderr
+
+	var ws *WatcherSet
+	if len(watchers) > 0 {
+		if c.Stdin != nil {
+			return result, fmt.Errorf("执行(%s)失败: 设置了 Watcher 或 sudo 密码时不能指定标准输入", cmdline)
+		}
+		stdin, err := command.StdinPipe()
+		if err != nil {
+			return result, err
+		}
+		if ws, err = NewWatcherSet(stdin, watchers); err != nil {
+			return result, err
+		}
+		command.Stdout = ws.Writer(capture.Stdout, false)
+		command.Stderr = ws.Writer(capture.Stderr, true)
+	}
+
+	var serr *SentinelError
+	err := command.Start()
+	if err == nil {
+		serr, err = wait(ctx, command, time.Duration(grace)*time.Second, ws.Abort())
+	}
+	capture.Close()
+	result.EndTime = time.Now()
+	var decodeErr error
+	result.Stdout, result.Stderr, decodeErr = capture.Bytes()
+	result.Truncated = capture.Truncated()
+
+	if command.ProcessState != nil {
+		result.ExitCode = command.ProcessState.ExitCode()
+		if ws, ok := command.ProcessState.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			result.Signal = SignalName(ws.Signal())
+		}
+	}
 
-	stdout := new(bytes.Buffer)
-	stderr := new(bytes.Buffer)
-	command.Stdout = stdout
-	command.Stderr = stderr
+	// 匹配到 Sentinel 时命令可能恰好已经结束
+	if serr == nil && ws != nil {
+		select {
+		case serr = <-ws.Abort():
+		default:
+		}
+	}
+	if serr != nil {
+		serr.Result = result
+		return result, serr
+	}
 
-	err := command.Run()
+	if ctx.Err() != nil {
+		return result, &TimeoutError{Result: result, Err: ctx.Err()}
+	}
 
-	stdoutBytes, _ := GbkToUtf8(stdout.Bytes())
-	stderrBytes, _ := GbkToUtf8(stderr.Bytes())
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return result, &ExitError{Result: result}
+	}
+
+	if err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		return result, err
+	}
+	// 命令本身的错误优先返回, 命令执行成功但输出转换失败时返回转换的错误
+	return result, decodeErr
+}
+
+// wait 等待命令结束, ctx 结束或 abort 返回 SentinelError 时先向进程组发送 SIGTERM, grace 后仍未退出则发送 SIGKILL
+func wait(ctx context.Context, command *exec.Cmd, grace time.Duration, abort <-chan *SentinelError) (*SentinelError, error) {
+	done := make(chan error, 1)
+	go func() {
+		done <- command.Wait()
+	}()
+
+	var serr *SentinelError
+	select {
+	case err := <-done:
+		return nil, err
+	case serr = <-abort:
+	case <-ctx.Done():
+	}
 
-	if err != nil {
-		return stdoutBytes, stderrBytes, err
+	terminate(command)
+	select {
+	case err := <-done:
+		return serr, err
+	case <-time.After(grace):
 	}
 
-	return stdoutBytes, stderrBytes, nil
+	kill(command)
+	return serr, <-done
 }
 
 func GbkToUtf8(s []byte) ([]byte, error) {
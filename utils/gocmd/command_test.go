@@ -0,0 +1,246 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 15:02:44
+ */
+
+package gocmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestShellExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	sh := Shell{}
+	result, err := sh.Exec("echo hello; echo world >&2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Stdout) != "hello\n" || string(result.Stderr) != "world\n" || result.ExitCode != 0 || result.Host != LocalHost {
+		t.Errorf("执行结果不正确: %+v", result)
+	}
+
+	var exitErr *ExitError
+	if _, err := sh.Exec("exit 3"); !errors.As(err, &exitErr) || exitErr.ExitCode != 3 {
+		t.Errorf("应返回退出码为 3 的 ExitError, 实际: %v", err)
+	}
+
+	var timeoutErr *TimeoutError
+	sh = Shell{Timeout: 1}
+	if _, err := sh.Exec("echo partial; sleep 5"); !errors.As(err, &timeoutErr) || !timeoutErr.Timeout() {
+		t.Fatalf("应返回 TimeoutError, 实际: %v", err)
+	}
+	if string(timeoutErr.Stdout) != "partial\n" {
+		t.Errorf("超时错误中应包含已输出的内容, 实际: %q", timeoutErr.Stdout)
+	}
+}
+
+func TestShellOutput(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	var lines []Line
+	var tagged bytes.Buffer
+	sh := Shell{Output: Output{
+		Stdout:    &tagged,
+		OnLine:    func(l Line) { lines = append(lines, l) },
+		TagHost:   true,
+		StripANSI: true,
+		MaxBytes:  15,
+	}}
+
+	result, err := sh.Exec(`printf '\033[31mred\033[0m\nplain'; echo oops >&2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(result.Stdout) != "red\npl" || !result.Truncated {
+		t.Errorf("输出应截断为 15 字节并去掉颜色, 实际: %q, truncated: %v", result.Stdout, result.Truncated)
+	}
+	if tagged.String() != "[localhost] red\n[localhost] plain\n" {
+		t.Errorf("实时输出不正确: %q", tagged.String())
+	}
+
+	want := []Line{
+		{Host: LocalHost, Stream: StreamStdout, Text: "red"},
+		{Host: LocalHost, Stream: StreamStdout, Text: "plain"},
+		{Host: LocalHost, Stream: StreamStderr, Text: "oops"},
+	}
+	if !slices.Contains(lines, want[2]) || !slices.Equal(slices.DeleteFunc(slices.Clone(lines), func(l Line) bool { return l.Stream == StreamStderr }), want[:2]) {
+		t.Errorf("按行回调不正确: %+v", lines)
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	var out bytes.Buffer
+	lw := &lineWriter{host: LocalHost, stream: StreamStdout, w: &out, mu: new(sync.Mutex), output: Output{
+		OnLine: func(l Line) { lines = append(lines, l.Text) },
+	}}
+
+	// \r\n 跨两次写入时仍是一个换行, 单独的 \r 也作为换行
+	for _, p := range []string{"a\r", "\nb\r", "c\r\n", "10%\r20%\r", "tail"} {
+		_, _ = lw.Write([]byte(p))
+	}
+	lw.Flush()
+	if want := []string{"a", "b", "c", "10%", "20%", "tail"}; !slices.Equal(lines, want) {
+		t.Errorf("按行回调不正确: %q, 期望: %q", lines, want)
+	}
+	if out.String() != "a\nb\nc\n10%\n20%\ntail\n" {
+		t.Errorf("实时输出不正确: %q", out.String())
+	}
+
+	// 没有换行的输出超过 maxLineBytes 时按字符边界拆分, 不会无限缓存
+	lines = nil
+	_, _ = lw.Write([]byte("ab" + strings.Repeat("中", maxLineBytes/3)))
+	if len(lw.buf) >= maxLineBytes || len(lines) != 1 || !utf8.ValidString(lines[0]) || len(lines[0]) != maxLineBytes-2 {
+		t.Errorf("超长的行应按字符边界拆分, 缓存: %d 字节, 回调: %d 行", len(lw.buf), len(lines))
+	}
+	lw.Flush()
+	if len(lines) != 2 || lines[1] != "中" {
+		t.Errorf("剩余的内容应在 Flush 时转发, 实际: %d 行", len(lines))
+	}
+
+	gbk := Output{Encoding: EncodingGBK}
+	if n := gbk.Encoding.boundary([]byte{'a', 0xd6, 0xd0, 0xce}); n != 3 {
+		t.Errorf("GBK 不完整的字符前的长度应为 3, 实际: %d", n)
+	}
+}
+
+func TestShellRunContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	// 取消时连同子进程一起终止, 不会等到子进程结束
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+	sh := Shell{}
+	start := time.Now()
+	var timeoutErr *TimeoutError
+	if _, _, err := sh.RunContext(ctx, "sleep 30 | cat"); !errors.As(err, &timeoutErr) || timeoutErr.Timeout() {
+		t.Fatalf("应返回取消的 TimeoutError, 实际: %v", err)
+	}
+	if d := time.Since(start); d > 3*time.Second || timeoutErr.Signal != "TERM" {
+		t.Errorf("取消后应立即终止进程组, 耗时: %v, 信号: %s", d, timeoutErr.Signal)
+	}
+
+	// 忽略 SIGTERM 的进程在 KillGrace 后被 SIGKILL 终止
+	sh = Shell{Timeout: 1, KillGrace: 1}
+	start = time.Now()
+	if _, err := sh.Exec("trap '' TERM; sleep 30"); !errors.As(err, &timeoutErr) || !timeoutErr.Timeout() {
+		t.Fatalf("应返回超时的 TimeoutError, 实际: %v", err)
+	}
+	if d := time.Since(start); d > 4*time.Second || timeoutErr.Signal != "KILL" {
+		t.Errorf("超时后应在 KillGrace 后强制终止, 耗时: %v, 信号: %s", d, timeoutErr.Signal)
+	}
+}
+
+func TestShellExecCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	// 参数原样传给程序, 不经过 shell 解析
+	sh := Shell{}
+	arg := `it's "$HOME"; rm -rf /`
+	stdout, _, err := sh.RunArgs("printf", "%s", arg)
+	if err != nil || string(stdout) != arg {
+		t.Fatalf("参数应原样传递: %q, %v", stdout, err)
+	}
+
+	dir := t.TempDir()
+	c := Command{Name: "sh", Args: []string{"-c", `pwd; echo "$GOCMD_TEST"; cat`}, Env: []string{"GOCMD_TEST=a b"}, Dir: dir, Stdin: strings.NewReader("input")}
+	result, err := sh.ExecCommand(context.Background(), c)
+	if err != nil || string(result.Stdout) != dir+"\na b\ninput" {
+		t.Errorf("环境变量、工作目录或标准输入不正确: %q, %v", result.Stdout, err)
+	}
+
+	// String 转义后交给 shell 执行, 结果与直接执行相同
+	c.Stdin = nil
+	c.Args = []string{"-c", `pwd; echo "$GOCMD_TEST"`}
+	result, err = sh.Exec(c.String())
+	if err != nil || string(result.Stdout) != dir+"\na b\n" {
+		t.Errorf("转义后的命令执行结果不正确: %s: %q, %v", c.String(), result.Stdout, err)
+	}
+}
+
+// fakeSudo 从标准输入读取密码并与 GOCMD_TEST_SUDO_PASSWORD 比较, -n 时直接失败, 命令以当前用户执行
+const fakeSudo = `#!/bin/sh
+prompt='Password: '
+while [ $# -gt 0 ]; do
+	case "$1" in
+		-p) prompt=$2; shift 2 ;;
+		-u) shift 2 ;;
+		-n) echo "sudo: a password is required" >&2; exit 1 ;;
+		--) shift; break ;;
+		-*) shift ;;
+		*) break ;;
+	esac
+done
+printf '%s' "$prompt" >&2
+IFS= read -r password || exit 1
+if [ "$password" != "$GOCMD_TEST_SUDO_PASSWORD" ]; then
+	echo "Sorry, try again." >&2
+	read -r password
+	exit 1
+fi
+exec "$@"
+`
+
+func TestShellSudo(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "sudo"), []byte(fakeSudo), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("GOCMD_TEST_SUDO_PASSWORD", "secret")
+
+	var passwordErr *PasswordRequiredError
+	sh := Shell{}
+	if _, err := sh.SudoExec("true"); !errors.As(err, &passwordErr) {
+		t.Errorf("没有 sudo 密码时应返回 PasswordRequiredError, 实际: %v", err)
+	}
+
+	sh = Shell{SudoPassword: "secret"}
+	if stdout, _, err := sh.Sudo("echo ok"); err != nil || string(stdout) != "ok\n" {
+		t.Errorf("sudo 执行失败: %q, %v", stdout, err)
+	}
+	// 命令中的引号和变量由 sudo 之后的 bash 解析
+	if stdout, _, err := sh.Sudo(`x="a  b"; echo "$x" 'c'`); err != nil || string(stdout) != "a  b c\n" {
+		t.Errorf("sudo 执行含引号的命令不正确: %q, %v", stdout, err)
+	}
+	if result, err := sh.SudoCommand(context.Background(), Command{Name: "printf", Args: []string{"%s", "$HOME"}}); err != nil || string(result.Stdout) != "$HOME" {
+		t.Errorf("sudo 执行参数不正确: %q, %v", result.Stdout, err)
+	}
+
+	var sentinelErr *SentinelError
+	sh = Shell{SudoPassword: "wrong", Timeout: 5}
+	start := time.Now()
+	if _, err := sh.SudoExec("true"); !errors.As(err, &sentinelErr) || time.Since(start) > 3*time.Second {
+		t.Errorf("sudo 密码错误应立即返回 SentinelError, 实际: %v", err)
+	}
+
+	sh = Shell{Watchers: []Watcher{{Pattern: "Continue? [y/N]", Response: "y"}}}
+	if stdout, _, err := sh.Run(`printf 'Continue? [y/N] '; read answer; echo "answer: $answer"`); err != nil || !strings.HasSuffix(string(stdout), "answer: y\n") {
+		t.Errorf("watcher 应答失败: %q, %v", stdout, err)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 14:02:36
+ */
+
+package gocmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/transform"
+)
+
+// Encoding 命令输出的编码, 设置后输出转换为 UTF-8
+type Encoding string
+
+const (
+	EncodingUTF8     Encoding = ""          // 不转换
+	EncodingGBK      Encoding = "gbk"       // 包括 GB2312, CP936
+	EncodingGB18030  Encoding = "gb18030"   // GBK 的超集
+	EncodingBig5     Encoding = "big5"      // 繁体中文
+	EncodingShiftJIS Encoding = "shift_jis" // 日文, 包括 CP932
+	// EncodingAuto 输出是合法的 UTF-8 时不转换, 否则依次尝试 GB18030, Big5, Shift-JIS, 使用第一个能完整转换的编码
+	EncodingAuto Encoding = "auto"
+)
+
+var encodings = map[Encoding]encoding.Encoding{
+	EncodingGBK:      simplifiedchinese.GBK,
+	EncodingGB18030:  simplifiedchinese.GB18030,
+	EncodingBig5:     traditionalchinese.Big5,
+	EncodingShiftJIS: japanese.ShiftJIS,
+}
+
+// autoEncodings EncodingAuto 依次尝试的编码
+var autoEncodings = []Encoding{EncodingGB18030, EncodingBig5, EncodingShiftJIS}
+
+// 字符集名称(转换为小写并去掉 - 和 _ 后)对应的编码
+var charsets = map[string]Encoding{
+	"gbk":       EncodingGBK,
+	"gb2312":    EncodingGBK,
+	"cp936":     EncodingGBK,
+	"gb18030":   EncodingGB18030,
+	"big5":      EncodingBig5,
+	"big5hkscs": EncodingBig5,
+	"sjis":      EncodingShiftJIS,
+	"shiftjis":  EncodingShiftJIS,
+	"cp932":     EncodingShiftJIS,
+}
+
+// EncodingFromLocale 根据 locale 中的字符集返回对应的编码, 如 zh_CN.GBK 返回 EncodingGBK
+// UTF-8 和不认识的字符集返回 EncodingUTF8
+func EncodingFromLocale(locale string) Encoding {
+	_, charset, ok := strings.Cut(locale, ".")
+	if !ok {
+		return EncodingUTF8
+	}
+	charset, _, _ = strings.Cut(charset, "@")
+	charset = strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(charset))
+	return charsets[charset]
+}
+
+// DecodeError 输出中有不符合编码的字节, 这些字节已被替换为 U+FFFD
+type DecodeError struct {
+	Encoding Encoding
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("输出不是合法的 %s 编码", e.Encoding)
+}
+
+// Decode 将 b 从编码 e 转换为 UTF-8
+// 有不符合编码的字节时返回替换为 U+FFFD 后的结果和 *DecodeError, 不支持的编码返回 b 和错误
+func (e Encoding) Decode(b []byte) ([]byte, error) {
+	switch e {
+	case EncodingUTF8:
+		return b, nil
+	case EncodingAuto:
+		if utf8.Valid(b) {
+			return b, nil
+		}
+		var first []byte
+		for _, enc := range autoEncodings {
+			d, err := enc.Decode(b)
+			if err == nil {
+				return d, nil
+			}
+			if first == nil {
+				first = d
+			}
+		}
+		return first, &DecodeError{Encoding: e}
+	}
+
+	enc, ok := encodings[e]
+	if !ok {
+		return b, fmt.Errorf("不支持的编码: %s", e)
+	}
+	d, _, err := transform.Bytes(enc.NewDecoder(), b)
+	if err != nil {
+		return b, fmt.Errorf("转换 %s 编码失败: %v", e, err)
+	}
+	// 解码器将不符合编码的字节替换为 U+FFFD, 不返回错误. 原始输出中本身就有的 U+FFFD(如 GB18030 的 0x8431A437)不算转换失败
+	replacement := []byte(string(utf8.RuneError))
+	if bytes.Count(d, replacement) > encodedCount(enc, b, replacement) {
+		return d, &DecodeError{Encoding: e}
+	}
+	return d, nil
+}
+
+// encodedCount 返回 b 中按编码 enc 编码后的 s 出现的次数, s 不能用 enc 编码时返回 0
+func encodedCount(enc encoding.Encoding, b []byte, s []byte) int {
+	encoded, err := enc.NewEncoder().Bytes(s)
+	if err != nil {
+		return 0
+	}
+	return bytes.Count(b, encoded)
+}
+
+// boundary 返回 b 中不以不完整的字符结尾的最长前缀的长度, 用于按字符边界拆分输出
+func (e Encoding) boundary(b []byte) int {
+	if enc, ok := encodings[e]; ok {
+		// atEOF 为 false 时, 解码器在末尾不完整的字符前停止
+		_, n, _ := enc.NewDecoder().Transform(make([]byte, len(b)*3+utf8.UTFMax), b, false)
+		return n
+	}
+
+	// UTF-8 和 EncodingAuto 按 UTF-8 处理, 最多回退一个字符
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(b)
+}
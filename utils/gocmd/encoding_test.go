@@ -0,0 +1,80 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 14:40:12
+ */
+
+package gocmd
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestEncodingDecode(t *testing.T) {
+	gbk := []byte{0xd6, 0xd0, 0xce, 0xc4} // "中文"
+	tests := []struct {
+		enc     Encoding
+		in      []byte
+		want    string
+		invalid bool
+	}{
+		{EncodingUTF8, []byte("中文"), "中文", false},
+		{EncodingGBK, gbk, "中文", false},
+		{EncodingGB18030, gbk, "中文", false},
+		{EncodingBig5, []byte{0xa4, 0xa4, 0xa4, 0xe5}, "中文", false},
+		{EncodingShiftJIS, []byte{0x93, 0xfa, 0x96, 0x7b}, "日本", false},
+		{EncodingAuto, []byte("中文"), "中文", false},
+		{EncodingAuto, gbk, "中文", false},
+		{EncodingGBK, []byte{'a', 0xff, 'b'}, "a�b", true},
+		// 原始输出中本身就有的 U+FFFD 不算转换失败
+		{EncodingUTF8, []byte("a\uFFFDb"), "a\uFFFDb", false},
+		{EncodingAuto, []byte("a\uFFFDb"), "a\uFFFDb", false},
+		{EncodingGB18030, []byte{'a', 0x84, 0x31, 0xa4, 0x37}, "a\uFFFD", false},
+		{EncodingGB18030, []byte{'a', 0x84, 0x31, 0xa4, 0x37, 0xff}, "a\uFFFD\uFFFD", true},
+	}
+
+	for _, tt := range tests {
+		got, err := tt.enc.Decode(tt.in)
+		var decodeErr *DecodeError
+		if string(got) != tt.want || errors.As(err, &decodeErr) != tt.invalid {
+			t.Errorf("%s: Decode(%x) = %q, %v, 期望: %q", tt.enc, tt.in, got, err, tt.want)
+		}
+	}
+
+	for locale, want := range map[string]Encoding{"zh_CN.GBK": EncodingGBK, "zh_TW.Big5": EncodingBig5, "ja_JP.SJIS": EncodingShiftJIS, "zh_CN.gb18030@euro": EncodingGB18030, "en_US.UTF-8": EncodingUTF8, "C": EncodingUTF8} {
+		if got := EncodingFromLocale(locale); got != want {
+			t.Errorf("EncodingFromLocale(%s) = %q, 期望: %q", locale, got, want)
+		}
+	}
+}
+
+func TestShellEncoding(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	var lines []string
+	sh := Shell{Output: Output{Encoding: EncodingGBK, OnLine: func(l Line) { lines = append(lines, l.Text) }}}
+	stdout, _, err := sh.Run(`printf '\326\320\316\304\n'`)
+	if err != nil || string(stdout) != "中文\n" || len(lines) != 1 || lines[0] != "中文" {
+		t.Errorf("输出转换不正确: %q, %q, %v", stdout, lines, err)
+	}
+
+	// 按 MaxBytes 截断时不拆分多字节字符, 不返回 DecodeError
+	sh.Output.MaxBytes = 3
+	if stdout, _, err = sh.Run(`printf '\326\320\316\304'`); err != nil || string(stdout) != "中" {
+		t.Errorf("截断后应保留完整的字符: %q, %v", stdout, err)
+	}
+	utf8Shell := Shell{Output: Output{MaxBytes: 5}}
+	if stdout, _, err = utf8Shell.Run(`printf '中文'`); err != nil || string(stdout) != "中" {
+		t.Errorf("截断后应保留完整的字符: %q, %v", stdout, err)
+	}
+	sh.Output.MaxBytes = 0
+
+	// 转换失败时返回错误和替换后的输出
+	var decodeErr *DecodeError
+	if stdout, _, err = sh.Run(`printf 'a\377b'`); !errors.As(err, &decodeErr) || string(stdout) != "a�b" {
+		t.Errorf("应返回 DecodeError, 实际: %q, %v", stdout, err)
+	}
+}
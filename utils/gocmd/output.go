@@ -0,0 +1,251 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 22:05:18
+ */
+
+package gocmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/lsne/goutils/utils/strutil"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// maxLineBytes 按行处理时一行最多缓存的字节数, 超过时不等换行直接转发, 避免长时间没有换行的输出占用过多内存
+const maxLineBytes = 64 * 1024
+
+// Line 命令输出的一行, 不包含换行符
+type Line struct {
+	Host   string // 执行命令的机器, 本地执行时为 LocalHost
+	Stream string // StreamStdout 或 StreamStderr
+	Text   string
+}
+
+// Output 命令输出的实时转发和缓存设置, 本地(Shell)和远程(gossh.Connection)执行使用相同的设置
+type Output struct {
+	Stdout    io.Writer  // 实时写入标准输出
+	Stderr    io.Writer  // 实时写入标准错误
+	OnLine    func(Line) // 每输出一行回调一次, \n, \r\n 和 \r(如进度条)都作为换行, 超过 64KB 没有换行时拆分为多行. 标准输出和标准错误的回调不会并发执行
+	TagHost   bool       // 写入 Stdout/Stderr 时每行加上 "[host] " 前缀
+	StripANSI bool       // 去掉 ANSI 转义序列(颜色、光标控制等), 同时作用于实时输出和 Result 中的输出
+	MaxBytes  int        // Result.Stdout/Result.Stderr 各自最多保留的字节数(按原始输出计算), 超出部分丢弃, 不会拆分多字节字符. 小于等于 0 时不限制
+	Encoding  Encoding   // 输出的编码, 同时作用于实时输出和 Result 中的输出. 为空时不转换, 执行时设置了非 UTF-8 的 locale 则使用其字符集
+}
+
+// Capture 按 Output 的设置缓存命令输出并实时转发
+// Stdout 和 Stderr 作为命令的输出, 命令结束后调用 Close 转发最后不以换行结尾的内容
+type Capture struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	host   string
+	output Output
+	stdout *limitBuffer
+	stderr *limitBuffer
+	lines  []*lineWriter
+}
+
+// NewCapture 创建在机器 host 上执行命令时使用的 Capture
+func (o Output) NewCapture(host string) *Capture {
+	c := &Capture{
+		host:   host,
+		output: o,
+		stdout: &limitBuffer{max: o.MaxBytes},
+		stderr: &limitBuffer{max: o.MaxBytes},
+	}
+
+	// 标准输出和标准错误可能写到同一个 Writer, 共用一把锁
+	mu := new(sync.Mutex)
+	c.Stdout = c.tee(c.stdout, host, StreamStdout, o.Stdout, mu)
+	c.Stderr = c.tee(c.stderr, host, StreamStderr, o.Stderr, mu)
+	return c
+}
+
+func (c *Capture) tee(buf *limitBuffer, host, stream string, w io.Writer, mu *sync.Mutex) io.Writer {
+	o := c.output
+	if w == nil && o.OnLine == nil {
+		return buf
+	}
+
+	// 不需要按行处理时直接转发, 不以换行结尾的内容(如密码提示)也能立即输出
+	if o.OnLine == nil && !o.TagHost && !o.StripANSI && o.Encoding == EncodingUTF8 {
+		return io.MultiWriter(buf, &lockedWriter{w: w, mu: mu})
+	}
+
+	lw := &lineWriter{host: host, stream: stream, w: w, mu: mu, output: o}
+	c.lines = append(c.lines, lw)
+	return io.MultiWriter(buf, lw)
+}
+
+// Close 转发最后不以换行结尾的内容
+func (c *Capture) Close() {
+	for _, lw := range c.lines {
+		lw.Flush()
+	}
+}
+
+// Bytes 返回当前缓存的标准输出和标准错误, 命令执行过程中也可以调用
+// 按 Encoding 转换失败时仍然返回转换后的输出(不合法的字节替换为 U+FFFD), 同时返回错误
+func (c *Capture) Bytes() (stdout []byte, stderr []byte, err error) {
+	stdout, stdoutErr := c.output.Encoding.Decode(c.raw(c.stdout))
+	stderr, stderrErr := c.output.Encoding.Decode(c.raw(c.stderr))
+	if stdoutErr != nil {
+		err = fmt.Errorf("在机器: %s 上, 转换命令的标准输出失败: %w", c.host, stdoutErr)
+	} else if stderrErr != nil {
+		err = fmt.Errorf("在机器: %s 上, 转换命令的标准错误失败: %w", c.host, stderrErr)
+	}
+
+	if c.output.StripANSI {
+		stdout, stderr = []byte(strutil.StripANSI(string(stdout))), []byte(strutil.StripANSI(string(stderr)))
+	}
+	return stdout, stderr, err
+}
+
+// raw 返回 buf 中缓存的原始输出, 被截断时去掉末尾不完整的字符, 避免转换时被当作不合法的字节
+func (c *Capture) raw(buf *limitBuffer) []byte {
+	b, truncated := buf.snapshot()
+	if truncated {
+		b = b[:c.output.Encoding.boundary(b)]
+	}
+	return b
+}
+
+// Truncated 输出是否超过 MaxBytes 被截断
+func (c *Capture) Truncated() bool {
+	return c.stdout.Truncated() || c.stderr.Truncated()
+}
+
+// limitBuffer 并发安全, 最多保留 max 字节的 bytes.Buffer
+type limitBuffer struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *limitBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	n := len(p)
+	if b.max > 0 && b.buf.Len()+n > b.max {
+		p = p[:b.max-b.buf.Len()]
+		b.truncated = true
+	}
+	b.buf.Write(p)
+	return n, nil
+}
+
+// snapshot 返回当前内容的拷贝和是否被截断
+func (b *limitBuffer) snapshot() ([]byte, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return bytes.Clone(b.buf.Bytes()), b.truncated
+}
+
+func (b *limitBuffer) Truncated() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.truncated
+}
+
+type lockedWriter struct {
+	w  io.Writer
+	mu *sync.Mutex
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = l.w.Write(p)
+	return len(p), nil
+}
+
+// lineWriter 按行回调 OnLine, 并按 TagHost/StripANSI/Encoding 的设置写入 w
+// 转发失败不影响命令执行, 所以总是返回写入成功
+type lineWriter struct {
+	host   string
+	stream string
+	w      io.Writer
+	mu     *sync.Mutex
+	output Output
+	bufMu  sync.Mutex // 命令被中断时, Flush 可能与 Write 并发执行
+	buf    []byte
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	l.bufMu.Lock()
+	defer l.bufMu.Unlock()
+
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexAny(l.buf, "\r\n")
+		if i < 0 {
+			break
+		}
+		next := i + 1
+		if l.buf[i] == '\r' {
+			// \r 在末尾时等待后续的输出, 判断是否为 \r\n
+			if next == len(l.buf) {
+				break
+			}
+			if l.buf[next] == '\n' {
+				next++
+			}
+		}
+		l.writeLine(l.buf[:i])
+		l.buf = l.buf[next:]
+	}
+
+	// 超过 maxLineBytes 没有换行时按字符边界拆分
+	for len(l.buf) >= maxLineBytes {
+		n := l.output.Encoding.boundary(l.buf[:maxLineBytes])
+		if n == 0 {
+			n = maxLineBytes
+		}
+		l.writeLine(l.buf[:n])
+		l.buf = l.buf[n:]
+	}
+	return len(p), nil
+}
+
+// Flush 转发最后不以换行结尾的内容
+func (l *lineWriter) Flush() {
+	l.bufMu.Lock()
+	defer l.bufMu.Unlock()
+
+	if len(l.buf) > 0 {
+		l.writeLine(bytes.TrimSuffix(l.buf, []byte("\r")))
+		l.buf = nil
+	}
+}
+
+func (l *lineWriter) writeLine(b []byte) {
+	// 转换失败的错误在 Capture.Bytes 中返回, 这里只输出替换后的内容
+	b, _ = l.output.Encoding.Decode(b)
+	text := string(b)
+	if l.output.StripANSI {
+		text = strutil.StripANSI(text)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.output.OnLine != nil {
+		l.output.OnLine(Line{Host: l.host, Stream: l.stream, Text: text})
+	}
+	if l.w != nil {
+		if l.output.TagHost {
+			text = "[" + l.host + "] " + text
+		}
+		_, _ = io.WriteString(l.w, text+"\n")
+	}
+}
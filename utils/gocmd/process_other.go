@@ -0,0 +1,24 @@
+//go:build !unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 10:42:19
+ */
+
+package gocmd
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup 非 unix 系统不支持进程组, 只能终止命令本身
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// terminate 非 unix 系统没有 SIGTERM, 直接终止进程
+func terminate(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
+
+func kill(cmd *exec.Cmd) {
+	_ = cmd.Process.Kill()
+}
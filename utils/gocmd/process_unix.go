@@ -0,0 +1,31 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 10:42:19
+ */
+
+package gocmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup 让命令在单独的进程组中执行, 超时时可以连同子进程一起终止
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// terminate 向命令所在的进程组发送 SIGTERM
+func terminate(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
+}
+
+// kill 向命令所在的进程组发送 SIGKILL
+func kill(cmd *exec.Cmd) {
+	_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
@@ -0,0 +1,124 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 14:35:08
+ */
+
+package gocmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+)
+
+// LocalHost 本地执行命令时 Result.Host 的值
+const LocalHost = "localhost"
+
+// Result 命令执行结果, 本地(Shell)和远程(gossh.Connection)执行返回相同的结构
+type Result struct {
+	Host      string // 执行命令的机器, 本地执行时为 LocalHost
+	Cmd       string // 最终执行的完整命令
+	ExitCode  int    // 退出码, 未能获取到退出码(如被信号终止、连接断开)时为 -1
+	Signal    string // 被信号终止时的信号名, 如 "KILL", "TERM"
+	Stdout    []byte
+	Stderr    []byte
+	Truncated bool // 输出超过 Output.MaxBytes 被截断
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// Duration 命令执行耗时
+func (r *Result) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// Success 命令是否正常退出且退出码为 0
+func (r *Result) Success() bool {
+	return r.ExitCode == 0 && r.Signal == ""
+}
+
+// ExitError 命令已执行, 但退出码不为 0 或被信号终止
+type ExitError struct {
+	*Result
+}
+
+func (e *ExitError) Error() string {
+	if e.Signal != "" {
+		return fmt.Sprintf("进程被信号 %s 终止", e.Signal)
+	}
+	return fmt.Sprintf("退出码: %d", e.ExitCode)
+}
+
+// TimeoutError 命令执行超时或被取消时返回的错误, Result 中包含已经捕获到的部分输出
+type TimeoutError struct {
+	*Result
+	Err error // context.DeadlineExceeded 或 context.Canceled
+}
+
+func (e *TimeoutError) Error() string {
+	if e.Timeout() {
+		return fmt.Sprintf("在机器: %s 上, 执行(%s)超时: %v", e.Host, e.Cmd, e.Err)
+	}
+	return fmt.Sprintf("在机器: %s 上, 执行(%s)被取消: %v", e.Host, e.Cmd, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout 是否是因为超时(而非主动取消)而中断
+func (e *TimeoutError) Timeout() bool {
+	return errors.Is(e.Err, context.DeadlineExceeded)
+}
+
+// PasswordRequiredError sudo 需要密码, 但没有设置 sudo 密码
+type PasswordRequiredError struct {
+	*Result
+}
+
+func (e *PasswordRequiredError) Error() string {
+	return fmt.Sprintf("在机器: %s 上, 执行(%s)失败: sudo 需要密码, 请设置 sudo 密码或为用户配置 NOPASSWD", e.Host, e.Cmd)
+}
+
+// sudoPasswordRequired 以 sudo -n 执行时, 需要密码的错误信息
+// 不同版本的 sudo 分别输出 "a password is required" 和 "no password was provided"
+var sudoPasswordRequired = []string{"a password is required", "no password was provided"}
+
+// SudoPasswordRequiredError 命令以退出码 1 退出, 且输出中包含 sudo 需要密码的错误信息时, 返回 *PasswordRequiredError, 否则原样返回 err
+// 使用 pty 时标准错误合并到标准输出, 所以同时检查两者
+func SudoPasswordRequiredError(result *Result, err error) error {
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || result.ExitCode != 1 {
+		return err
+	}
+	for _, out := range [][]byte{result.Stderr, result.Stdout} {
+		for _, msg := range sudoPasswordRequired {
+			if bytes.Contains(out, []byte("sudo: "+msg)) {
+				return &PasswordRequiredError{Result: result}
+			}
+		}
+	}
+	return err
+}
+
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:  "HUP",
+	syscall.SIGINT:  "INT",
+	syscall.SIGQUIT: "QUIT",
+	syscall.SIGABRT: "ABRT",
+	syscall.SIGKILL: "KILL",
+	syscall.SIGSEGV: "SEGV",
+	syscall.SIGPIPE: "PIPE",
+	syscall.SIGTERM: "TERM",
+}
+
+// SignalName 返回与 ssh 协议一致的信号名(不带 SIG 前缀)
+func SignalName(sig syscall.Signal) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	return sig.String()
+}
@@ -0,0 +1,139 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 16:20:31
+ */
+
+package gocmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// 重试策略的默认值
+const (
+	DefaultRetryDelay    = time.Second
+	DefaultRetryMaxDelay = 30 * time.Second
+	DefaultRetryFactor   = 2.0
+)
+
+// TransientPatterns 输出中出现这些内容时, 认为是可以重试的临时错误
+var TransientPatterns = []string{
+	"Could not get lock",                            // apt/dpkg 锁被占用
+	"Unable to acquire the dpkg frontend lock",      // apt
+	"Another app is currently holding the yum lock", // yum
+	"waiting for process with pid",                  // dnf
+	"job is running",                                // systemd 服务的其他操作正在进行
+	"Transaction is in progress",                    // systemd
+	"Resource temporarily unavailable",
+}
+
+// RetryPredicate 判断一次执行的结果是否需要重试, err 为 nil 时不会被调用
+type RetryPredicate func(result *Result, err error) bool
+
+// RetryPolicy 命令执行失败后的重试策略, 第 n 次重试前等待 Delay * Factor^(n-1), 最多 MaxDelay, 并随机浮动 Jitter 比例
+// 每次重试都是完整的重新执行, 超时时间单独计算. ctx 被取消时不再重试
+type RetryPolicy struct {
+	MaxAttempts int            // 最多执行的次数(包括第一次), 小于等于 1 时不重试
+	Delay       time.Duration  // 第一次重试前的等待时间, 为 0 时使用 DefaultRetryDelay
+	MaxDelay    time.Duration  // 最长等待时间, 为 0 时使用 DefaultRetryMaxDelay
+	Factor      float64        // 每次重试等待时间的倍数, 小于 1 时使用 DefaultRetryFactor
+	Jitter      float64        // 等待时间随机浮动的比例, 取值 0 到 1, 如 0.2 表示在 ±20% 范围内浮动
+	RetryIf     RetryPredicate // 是否重试, 为 nil 时使用 RetryOnTransient
+}
+
+// Do 按策略执行 fn, 返回最后一次执行的结果
+func (p *RetryPolicy) Do(ctx context.Context, fn func() (*Result, error)) (*Result, error) {
+	retryIf := p.RetryIf
+	if retryIf == nil {
+		retryIf = RetryOnTransient
+	}
+
+	for attempt := 1; ; attempt++ {
+		result, err := fn()
+		if err == nil || attempt >= p.MaxAttempts || ctx.Err() != nil || !retryIf(result, err) {
+			return result, err
+		}
+
+		timer := time.NewTimer(p.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff 第 attempt 次执行失败后的等待时间
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay, maxDelay, factor := p.Delay, p.MaxDelay, p.Factor
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+	if factor < 1 {
+		factor = DefaultRetryFactor
+	}
+
+	d := min(float64(delay)*math.Pow(factor, float64(attempt-1)), float64(maxDelay))
+	if p.Jitter > 0 {
+		d *= 1 + min(p.Jitter, 1)*(rand.Float64()*2-1)
+	}
+	return time.Duration(d)
+}
+
+// RetryOnTransient 标准输出或标准错误中包含 TransientPatterns 时重试
+func RetryOnTransient(result *Result, err error) bool {
+	return RetryOnOutput(TransientPatterns...)(result, err)
+}
+
+// RetryOnExitCode 退出码为 codes 之一时重试
+func RetryOnExitCode(codes ...int) RetryPredicate {
+	return func(result *Result, err error) bool {
+		var exitErr *ExitError
+		return errors.As(err, &exitErr) && exitErr.Signal == "" && slices.Contains(codes, exitErr.ExitCode)
+	}
+}
+
+// RetryOnOutput 标准输出或标准错误中包含 patterns 之一时重试. 使用 pty 执行的远程命令, 标准错误合并在标准输出中
+func RetryOnOutput(patterns ...string) RetryPredicate {
+	return func(result *Result, err error) bool {
+		if result == nil {
+			return false
+		}
+		for _, pattern := range patterns {
+			if bytes.Contains(result.Stderr, []byte(pattern)) || bytes.Contains(result.Stdout, []byte(pattern)) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryOnError 错误链中有 T 类型的错误时重试, 如 RetryOnError[*gossh.TransportError]()
+func RetryOnError[T error]() RetryPredicate {
+	return func(result *Result, err error) bool {
+		var target T
+		return errors.As(err, &target)
+	}
+}
+
+// RetryAny 任意一个条件满足时重试
+func RetryAny(predicates ...RetryPredicate) RetryPredicate {
+	return func(result *Result, err error) bool {
+		for _, predicate := range predicates {
+			if predicate(result, err) {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 16:52:17
+ */
+
+package gocmd
+
+import (
+	"errors"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	p := RetryPolicy{Delay: 100 * time.Millisecond, MaxDelay: time.Second, Factor: 3, Jitter: 0.5}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 300 * time.Millisecond, 3: 900 * time.Millisecond, 4: time.Second} {
+		if d := p.backoff(attempt); d < want/2 || d > want*3/2 {
+			t.Errorf("第 %d 次重试的等待时间 %v 不在 %v ±50%% 范围内", attempt, d, want)
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	// 前两次以退出码 75 失败, 第三次成功
+	counter := filepath.Join(t.TempDir(), "counter")
+	cmd := "n=$(cat " + counter + " 2>/dev/null || echo 0); n=$((n+1)); echo $n > " + counter + "; [ $n -ge 3 ] || exit 75"
+	sh := &Shell{}
+	retry := RetryPolicy{MaxAttempts: 3, Delay: time.Millisecond, RetryIf: RetryOnExitCode(75)}
+	if _, _, err := sh.WithRetry(retry).Run(cmd); err != nil {
+		t.Errorf("重试后应执行成功: %v", err)
+	}
+	if sh.Retry != nil {
+		t.Error("WithRetry 不应修改原来的 Shell")
+	}
+
+	// 不满足重试条件时不重试
+	var exitErr *ExitError
+	retry = RetryPolicy{MaxAttempts: 5, Delay: time.Millisecond, RetryIf: RetryAny(RetryOnOutput("lock"), RetryOnExitCode(1))}
+	start := time.Now()
+	if _, _, err := sh.WithRetry(retry).Run("echo busy; exit 2"); !errors.As(err, &exitErr) || exitErr.ExitCode != 2 {
+		t.Errorf("应返回退出码为 2 的 ExitError, 实际: %v", err)
+	}
+	retry.RetryIf = nil
+	if _, _, err := sh.WithRetry(retry).Run("echo 'E: Could not get lock /var/lib/dpkg/lock' >&2; exit 100"); !errors.As(err, &exitErr) {
+		t.Errorf("应返回 ExitError, 实际: %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Errorf("重试耗时过长: %v", time.Since(start))
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * @Author: lsne
+ * @Date: 2025-11-10 19:40:13
+ */
+
+package gocmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Watcher 扫描命令的输出, 匹配到 Pattern 时向标准输入写入 Response, 用于应答交互式命令的提示
+type Watcher struct {
+	Pattern  string // 要捕获字符串, 为空时只检查 Sentinel
+	Response string // 捕获到匹配的字符串出现后, 要输入的内容
+	Sentinel string // 应答后返回的信息与之匹配, 说明应答失败, 中止命令并返回 SentinelError. Pattern 为空时始终检查
+	ToUpper  bool   // 是否将捕获的字符串和要匹配的字符串全转换成大写再进行比较
+	Regexp   bool   // Pattern 和 Sentinel 是否为正则表达式
+	Once     bool   // 只应答一次, 默认每次匹配都应答
+	Stderr   bool   // 是否同时扫描标准错误. 使用 pty 时远程命令的标准错误会合并到标准输出, sudo 的提示输出到标准错误
+}
+
+// SentinelError 应答后的输出匹配到 Watcher.Sentinel, 命令已被中止
+type SentinelError struct {
+	*Result
+	Pattern  string // 触发中止的 Watcher 的 Pattern
+	Sentinel string // 匹配到的 Watcher.Sentinel
+	Line     string // 匹配到 Sentinel 的输出行
+}
+
+func (e *SentinelError) Error() string {
+	return fmt.Sprintf("在机器: %s 上, 执行(%s)时应答失败: %s", e.Host, e.Cmd, e.Line)
+}
+
+type watchState struct {
+	Watcher
+	pattern  *regexp.Regexp
+	sentinel *regexp.Regexp
+	answered bool
+}
+
+func (s *watchState) match(re *regexp.Regexp, literal string, line string) bool {
+	if literal == "" {
+		return false
+	}
+	if re != nil {
+		return re.MatchString(line)
+	}
+	if s.ToUpper {
+		return strings.Contains(strings.ToUpper(line), strings.ToUpper(literal))
+	}
+	return strings.Contains(line, literal)
+}
+
+// WatcherSet 扫描命令的输出并应答, 标准输出和标准错误在不同的 goroutine 中扫描, 共享应答状态
+type WatcherSet struct {
+	mu      sync.Mutex
+	in      io.Writer
+	states  []*watchState
+	abort   chan *SentinelError
+	aborted bool
+}
+
+// NewWatcherSet 应答写入 in, 通常是命令的标准输入. Regexp 为 true 的 Watcher 正则表达式错误时返回错误
+func NewWatcherSet(in io.Writer, wts []Watcher) (*WatcherSet, error) {
+	ws := &WatcherSet{in: in, abort: make(chan *SentinelError, 1)}
+	for _, wt := range wts {
+		s := &watchState{Watcher: wt}
+		if wt.Regexp {
+			var err error
+			if s.pattern, err = compileWatcher(wt.Pattern, wt.ToUpper); err != nil {
+				return nil, err
+			}
+			if s.sentinel, err = compileWatcher(wt.Sentinel, wt.ToUpper); err != nil {
+				return nil, err
+			}
+		}
+		ws.states = append(ws.states, s)
+	}
+	return ws, nil
+}
+
+func compileWatcher(expr string, ignoreCase bool) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("Watcher 正则表达式(%s)错误: %v", expr, err)
+	}
+	return re, nil
+}
+
+// Abort 匹配到 Sentinel 时返回 SentinelError, 只返回一次, 其中的 Result 由调用方填充. ws 为 nil 时返回 nil channel
+func (ws *WatcherSet) Abort() <-chan *SentinelError {
+	if ws == nil {
+		return nil
+	}
+	return ws.abort
+}
+
+// scan 检查当前行, 返回 true 表示已经应答, 调用方需要清空当前行, 避免同一行被重复应答
+func (ws *WatcherSet) scan(line string, stderr bool) bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.aborted {
+		return false
+	}
+
+	for _, s := range ws.states {
+		if stderr && !s.Stderr {
+			continue
+		}
+
+		if (s.Pattern == "" || s.answered) && s.match(s.sentinel, s.Sentinel, line) {
+			ws.aborted = true
+			ws.abort <- &SentinelError{Pattern: s.Pattern, Sentinel: s.Sentinel, Line: line}
+			return false
+		}
+
+		if s.Once && s.answered {
+			continue
+		}
+
+		if s.match(s.pattern, s.Pattern, line) {
+			s.answered = true
+			_, _ = io.WriteString(ws.in, s.Response+"\n")
+			return true
+		}
+	}
+	return false
+}
+
+// watchStream 按行累积一路输出并交给 watcherSet 检查
+// 每次写入只检查一次当前行(以及写入中结束的行), 当前行最多保留最后 maxLineBytes 字节, 与 lineWriter 相同
+type watchStream struct {
+	ws     *WatcherSet
+	stderr bool
+	line   []byte
+}
+
+func (s *watchStream) write(p []byte) {
+	if len(s.ws.states) == 0 {
+		return
+	}
+
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			s.append(p)
+			if s.ws.scan(string(s.line), s.stderr) {
+				s.line = s.line[:0]
+			}
+			return
+		}
+
+		// 行尾之前有新的输出时, 结束当前行之前检查一次, 已经检查过的内容不再重复检查
+		if i > 0 {
+			s.append(p[:i])
+			s.ws.scan(string(s.line), s.stderr)
+		}
+		s.line = s.line[:0]
+		p = p[i+1:]
+	}
+}
+
+// append 追加到当前行, 超过 maxLineBytes 时丢弃行首, 提示通常在行尾
+func (s *watchStream) append(p []byte) {
+	if len(p) >= maxLineBytes {
+		s.line = append(s.line[:0], p[len(p)-maxLineBytes:]...)
+		return
+	}
+	s.line = append(s.line, p...)
+	if n := len(s.line) - maxLineBytes; n > 0 {
+		s.line = append(s.line[:0], s.line[n:]...)
+	}
+}
+
+// Watch 按块读取标准输出写入 output, 并以同一块内容检查 Watcher, 直到 out 读取结束
+func (ws *WatcherSet) Watch(out io.Reader, output io.Writer) {
+	s := &watchStream{ws: ws}
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := out.Read(buf)
+		if n > 0 {
+			_, _ = output.Write(buf[:n])
+			s.write(buf[:n])
+		}
+		if err != nil {
+			break
+		}
+	}
+}
+
+// Writer 写入 w 的同时检查 Watcher, stderr 为 true 时只检查设置了 Stderr 的 Watcher
+func (ws *WatcherSet) Writer(w io.Writer, stderr bool) io.Writer {
+	return &watchWriter{s: &watchStream{ws: ws, stderr: stderr}, w: w}
+}
+
+type watchWriter struct {
+	s *watchStream
+	w io.Writer
+}
+
+func (w *watchWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.s.write(p[:n])
+	return n, err
+}
@@ -0,0 +1,90 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 21:40:55
+ */
+
+package gocmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// chunkWriter 记录 Write 的调用次数
+type chunkWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestWatcherSet(t *testing.T) {
+	var in bytes.Buffer
+	ws, err := NewWatcherSet(&in, []Watcher{
+		{Pattern: `(?i)password:\s*$`, Response: "secret", Regexp: true, Once: true, Sentinel: "Sorry, try again"},
+		{Pattern: "Remove anonymous users?", Response: "y"},
+		{Sentinel: "fatal", Stderr: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out chunkWriter
+	ws.Watch(strings.NewReader("Password: \nRemove anonymous users? \nRemove anonymous users? \nPassword: \n"), &out)
+	if got, want := in.String(), "secret\ny\ny\n"; got != want {
+		t.Errorf("应答内容: %q, 期望: %q", got, want)
+	}
+	if out.String() != "Password: \nRemove anonymous users? \nRemove anonymous users? \nPassword: \n" {
+		t.Errorf("输出内容不完整: %q", out.String())
+	}
+	if out.writes != 1 {
+		t.Errorf("输出应按块写入, 实际写入 %d 次", out.writes)
+	}
+
+	_, _ = ws.Writer(&bytes.Buffer{}, true).Write([]byte("Sorry, try again.\n"))
+	select {
+	case serr := <-ws.abort:
+		t.Fatalf("标准错误中不应检查未设置 Stderr 的 Watcher: %v", serr.Line)
+	default:
+	}
+
+	ws.Watch(strings.NewReader("Sorry, try again.\n"), &out)
+	select {
+	case serr := <-ws.abort:
+		var target *SentinelError
+		if !errors.As(error(serr), &target) || serr.Sentinel != "Sorry, try again" || serr.Line != "Sorry, try again." {
+			t.Errorf("SentinelError 不正确: %s, %q", serr.Sentinel, serr.Line)
+		}
+	default:
+		t.Fatal("没有匹配到 Sentinel")
+	}
+
+	// 按块写入: 提示跨两次写入, 超长的行只保留最后 maxLineBytes 字节
+	in.Reset()
+	ws, err = NewWatcherSet(&in, []Watcher{{Pattern: "Continue? [y/N]", Response: "y"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := ws.Writer(&bytes.Buffer{}, false)
+	_, _ = w.Write([]byte("done\nContinue? [y"))
+	_, _ = w.Write([]byte("/N] "))
+	for range 3 {
+		_, _ = w.Write(bytes.Repeat([]byte("x"), maxLineBytes-1))
+	}
+	if n := len(w.(*watchWriter).s.line); n != maxLineBytes {
+		t.Errorf("当前行应只保留最后 %d 字节, 实际: %d", maxLineBytes, n)
+	}
+	_, _ = w.Write([]byte("Continue? [y/N] "))
+	if got := in.String(); got != "y\ny\n" {
+		t.Errorf("按块写入时应答内容: %q, 期望: %q", got, "y\ny\n")
+	}
+
+	if _, err := NewWatcherSet(&in, []Watcher{{Pattern: "(", Regexp: true}}); err == nil {
+		t.Error("错误的正则表达式应该返回错误")
+	}
+}
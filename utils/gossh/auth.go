@@ -0,0 +1,187 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 17:40:52
+ */
+
+package gossh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/lsne/goutils/utils/logger"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// WithKeyFile 返回一个 ConnOption，用于设置私钥文件. 明确指定的私钥不可用时, 连接直接返回错误
+func WithKeyFile(file string) ConnOption {
+	return func(c *Connection) {
+		c.KeyFile = file
+	}
+}
+
+// WithKeyFiles 返回一个 ConnOption，用于追加私钥文件, 在 KeyFile 之后按顺序尝试
+// 与 ssh 的 IdentityFile 相同, 不存在、无法解析或已加密但没有提供私钥密码的私钥会被跳过
+func WithKeyFiles(files ...string) ConnOption {
+	return func(c *Connection) {
+		c.KeyFiles = append(c.KeyFiles, files...)
+	}
+}
+
+// WithPassphrase 返回一个 ConnOption，用于设置加密私钥的密码
+func WithPassphrase(passphrase string) ConnOption {
+	return func(c *Connection) {
+		c.Passphrase = passphrase
+	}
+}
+
+// WithAgent 返回一个 ConnOption，用于设置是否使用 SSH_AUTH_SOCK 指向的 ssh-agent 中的密钥
+func WithAgent(use bool) ConnOption {
+	return func(c *Connection) {
+		c.UseAgent = use
+	}
+}
+
+// WithKeyboardInteractive 返回一个 ConnOption，用于自定义 keyboard-interactive 认证的应答
+// 不设置时, 如果配置了 Password, 则对所有不回显的问题应答 Password
+func WithKeyboardInteractive(challenge ssh.KeyboardInteractiveChallenge) ConnOption {
+	return func(c *Connection) {
+		c.keyboardInteractive = challenge
+	}
+}
+
+// connectAgent 连接 ssh-agent
+func (conn *Connection) connectAgent() error {
+	if !conn.UseAgent || conn.shared.agentConn != nil {
+		return nil
+	}
+
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return fmt.Errorf("未设置环境变量 SSH_AUTH_SOCK, 无法使用 ssh-agent")
+	}
+
+	c, err := net.Dial("unix", sock)
+	if err != nil {
+		return fmt.Errorf("连接 ssh-agent(%s)失败: %v", sock, err)
+	}
+	conn.shared.agentConn = c
+	return nil
+}
+
+func (s *sshConn) closeAgent() {
+	if s.agentConn != nil {
+		s.agentConn.Close()
+		s.agentConn = nil
+	}
+}
+
+// authMethods 按 publickey(ssh-agent、私钥文件), password, keyboard-interactive 的顺序生成认证方式
+// ssh 客户端对同一种认证方式只会尝试一次, 所以所有的公钥必须放在同一个 PublicKeysCallback 中
+// keyfile 不可用时返回错误, keyfiles 中不可用的私钥只记录警告并跳过
+func (conn *Connection) authMethods(password, keyfile string, keyfiles []string) ([]ssh.AuthMethod, error) {
+	auth := make([]ssh.AuthMethod, 0)
+
+	var signers []ssh.Signer
+	if keyfile != "" {
+		signer, err := loadSigner(keyfile, conn.Passphrase)
+		if err != nil {
+			return nil, err
+		}
+		signers = append(signers, signer)
+	}
+	for _, file := range keyfiles {
+		if file == "" {
+			continue
+		}
+		signer, err := loadSigner(file, conn.Passphrase)
+		if err != nil {
+			logger.Warningf("跳过无法使用的私钥: %v", err)
+			continue
+		}
+		signers = append(signers, signer)
+	}
+
+	if agentConn := conn.shared.agentConn; len(signers) > 0 || agentConn != nil {
+		var agentClient agent.ExtendedAgent
+		if agentConn != nil {
+			agentClient = agent.NewClient(agentConn)
+		}
+		auth = append(auth, ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+			if agentClient == nil {
+				return signers, nil
+			}
+			agentSigners, err := agentClient.Signers()
+			if err != nil {
+				return signers, nil
+			}
+			// 与 ssh 相同, agent 中的密钥优先, 避免私钥文件过多时超过服务端的 MaxAuthTries
+			return append(agentSigners, signers...), nil
+		}))
+	}
+
+	if password != "" {
+		auth = append(auth, ssh.Password(password))
+	}
+
+	if conn.keyboardInteractive != nil {
+		auth = append(auth, ssh.KeyboardInteractive(conn.keyboardInteractive))
+	} else if password != "" {
+		auth = append(auth, ssh.KeyboardInteractive(func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range questions {
+				if !echos[i] {
+					answers[i] = password
+				}
+			}
+			return answers, nil
+		}))
+	}
+
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("没有可用的认证方式, 请指定密码、可用的私钥或使用 ssh-agent")
+	}
+	return auth, nil
+}
+
+// loadSigner 读取私钥文件, 支持加密私钥. 如果存在同名的 <keyfile>-cert.pub 证书文件, 则使用证书认证
+func loadSigner(keyfile, passphrase string) (ssh.Signer, error) {
+	key, err := os.ReadFile(keyfile)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+	var missingErr *ssh.PassphraseMissingError
+	if errors.As(err, &missingErr) {
+		if passphrase == "" {
+			return nil, fmt.Errorf("私钥(%s)已加密, 需要提供私钥密码", keyfile)
+		}
+		signer, err = ssh.ParsePrivateKeyWithPassphrase(key, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥(%s)失败: %w", keyfile, err)
+	}
+
+	certfile := keyfile + "-cert.pub"
+	certBytes, err := os.ReadFile(certfile)
+	if os.IsNotExist(err) {
+		return signer, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey(certBytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析证书(%s)失败: %w", certfile, err)
+	}
+	cert, ok := pub.(*ssh.Certificate)
+	if !ok {
+		return nil, fmt.Errorf("文件(%s)不是 ssh 证书", certfile)
+	}
+	return ssh.NewCertSigner(cert, signer)
+}
@@ -0,0 +1,106 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 18:02:19
+ */
+
+package gossh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func TestLoadSigner(t *testing.T) {
+	dir := t.TempDir()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyfile := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyfile, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := loadSigner(keyfile, ""); err == nil {
+		t.Error("加密私钥未提供密码时应返回错误")
+	}
+	if _, err := loadSigner(keyfile, "wrong"); err == nil {
+		t.Error("加密私钥密码错误时应返回错误")
+	}
+	signer, err := loadSigner(keyfile, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 同目录下存在 <keyfile>-cert.pub 时使用证书认证
+	_, caKey, _ := ed25519.GenerateKey(rand.Reader)
+	ca, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := &ssh.Certificate{Key: signer.PublicKey(), CertType: ssh.UserCert, ValidPrincipals: []string{"root"}, ValidBefore: ssh.CertTimeInfinity}
+	if err := cert.SignCert(rand.Reader, ca); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyfile+"-cert.pub", ssh.MarshalAuthorizedKey(cert), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err = loadSigner(keyfile, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := signer.PublicKey().(*ssh.Certificate); !ok {
+		t.Errorf("应使用证书认证, 实际公钥类型: %s", signer.PublicKey().Type())
+	}
+}
+
+func TestAuthMethodsSkipKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain, err := ssh.MarshalPrivateKey(priv, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	good, locked := filepath.Join(dir, "id_good"), filepath.Join(dir, "id_locked")
+	if err := os.WriteFile(good, pem.EncodeToMemory(plain), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(locked, pem.EncodeToMemory(encrypted), 0600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "id_missing")
+
+	conn := &Connection{shared: &sshConn{}}
+	if auth, err := conn.authMethods("", "", []string{locked, missing, good}); err != nil || len(auth) != 1 {
+		t.Errorf("KeyFiles 中不可用的私钥应被跳过, 实际: %d, %v", len(auth), err)
+	}
+	if _, err := conn.authMethods("", locked, []string{good}); err == nil {
+		t.Error("明确指定的私钥不可用时应返回错误")
+	}
+	if _, err := conn.authMethods("", "", []string{locked, missing}); err == nil {
+		t.Error("没有可用的认证方式时应返回错误")
+	}
+	if auth, err := conn.authMethods("p", "", []string{locked}); err != nil || len(auth) != 2 {
+		t.Errorf("私钥都不可用时应使用密码认证, 实际: %d, %v", len(auth), err)
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 22:48:36
+ */
+
+package gossh
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lsne/goutils/utils/strutil"
+	"golang.org/x/crypto/ssh"
+)
+
+// 登录时 PATH 可能不完整, 执行命令前追加常用目录
+const basePath = `export PATH="$PATH:/usr/bin:/usr/sbin"; `
+
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type envVar struct {
+	name  string
+	value string
+}
+
+// WithEnv 返回一个 Option，执行命令前导出环境变量 name=value, value 会被正确转义, 可以包含引号和 $
+// 不依赖 sshd 的 AcceptEnv 配置, 对 Sudo 同样有效
+func WithEnv(name, value string) Option {
+	return func(o *RunOptions) {
+		o.env = append(o.env, envVar{name: name, value: value})
+	}
+}
+
+// WithDir 返回一个 Option，用于设置执行命令的工作目录, 目录不存在时命令不执行, 退出码为 1
+// Sudo 默认在目标用户的家目录执行
+func WithDir(dir string) Option {
+	return func(o *RunOptions) {
+		o.dir = dir
+	}
+}
+
+// WithLocale 返回一个 Option，用于设置执行命令时的 LANG 和 LC_ALL, 如 "C", "en_US.UTF-8"
+func WithLocale(locale string) Option {
+	return func(o *RunOptions) {
+		o.locale = locale
+	}
+}
+
+// WithShell 返回一个 Option，用于设置执行命令的 shell, 如 "/bin/sh", "/bin/bash"
+// Run 默认由远程用户的登录 shell 执行, Sudo 默认使用 /bin/bash
+func WithShell(shell string) Option {
+	return func(o *RunOptions) {
+		o.shell = shell
+	}
+}
+
+func newRunOptions(opts []Option) *RunOptions {
+	options := &RunOptions{
+		hide:         false, // 默认显示输出
+		cancelSignal: ssh.SIGTERM,
+	}
+
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// script 在 cmd 前加上 PATH、locale、环境变量和切换工作目录的命令
+// home 为 true 且没有设置工作目录时, 切换到家目录
+func (o *RunOptions) script(cmd string, home bool) (string, error) {
+	var b strings.Builder
+	b.WriteString(basePath)
+
+	if o.locale != "" {
+		fmt.Fprintf(&b, "export LANG=%s LC_ALL=%s; ", strutil.Quote(o.locale), strutil.Quote(o.locale))
+	}
+
+	for _, env := range o.env {
+		if !envNamePattern.MatchString(env.name) {
+			return "", fmt.Errorf("环境变量名(%s)不合法", env.name)
+		}
+		fmt.Fprintf(&b, "export %s=%s; ", env.name, strutil.Quote(env.value))
+	}
+
+	if o.dir != "" {
+		fmt.Fprintf(&b, "cd %s || exit 1; ", strutil.Quote(o.dir))
+	} else if home {
+		b.WriteString("cd; ")
+	}
+
+	b.WriteString(cmd)
+	return b.String(), nil
+}
@@ -0,0 +1,40 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 22:59:02
+ */
+
+package gossh
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+
+	"github.com/lsne/goutils/utils/strutil"
+)
+
+func TestRunOptionsScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("仅支持类 unix 系统")
+	}
+
+	dir := t.TempDir()
+	options := newRunOptions([]Option{WithEnv("GREETING", `it's "$HOME"`), WithDir(dir), WithLocale("C")})
+	script, err := options.script(`printf '%s|%s|%s' "$GREETING" "$(pwd)" "$LC_ALL"`, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 与 Sudo 相同, 整个脚本作为一个参数再经过一层 shell
+	out, err := exec.Command("/bin/sh", "-c", "/bin/sh -c "+strutil.Quote(script)).CombinedOutput()
+	if err != nil {
+		t.Fatalf("执行失败: %v, 输出: %s", err, out)
+	}
+	if want := `it's "$HOME"|` + dir + "|C"; string(out) != want {
+		t.Errorf("输出: %q, 期望: %q", out, want)
+	}
+
+	if _, err := newRunOptions([]Option{WithEnv("A;B", "x")}).script("true", false); err == nil {
+		t.Error("不合法的环境变量名应该返回错误")
+	}
+}
@@ -6,16 +6,17 @@
 package gossh
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net"
 	"os"
 	"path"
 	"path/filepath"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/lsne/goutils/utils/fileutil"
@@ -28,12 +29,24 @@ import (
 
 const GOSSH_ERR_FORMAT = "在机器: %s 上, 执行(%s)失败: %v, 标准输出: %s, 标准错误: %s"
 
+// ctx 取消后等待远程会话结束的最长时间
+const cancelWait = 5 * time.Second
+
 type RunOptions struct {
 	hide         bool
 	sudoUser     string
 	sudoPassword string
 	sudoPattern  string
+	cancelSignal ssh.Signal
+	output       Output
+	env          []envVar
+	dir          string
+	locale       string
+	shell        string
 	Watchers     []Watcher
+	retry        *RetryPolicy
+	readOnly     bool
+	noPty        bool
 }
 
 // Option 是一个函数类型，用于修改 RunOptions
@@ -53,6 +66,92 @@ func WithWatchers(watchers ...Watcher) Option {
 	}
 }
 
+// WithCancelSignal 返回一个 Option，用于设置 ctx 取消或超时时发送给远程进程的信号, 默认 SIGTERM
+func WithCancelSignal(sig ssh.Signal) Option {
+	return func(o *RunOptions) {
+		o.cancelSignal = sig
+	}
+}
+
+// WithOutput 返回一个 Option，用于设置输出的实时转发、截断和 ANSI 过滤, 与 WithHide 互不影响
+func WithOutput(output Output) Option {
+	return func(o *RunOptions) {
+		o.output = output
+	}
+}
+
+// WithOutputWriters 返回一个 Option，用于将标准输出和标准错误实时写入 stdout 和 stderr, 为 nil 时不写入
+func WithOutputWriters(stdout, stderr io.Writer) Option {
+	return func(o *RunOptions) {
+		o.output.Stdout = stdout
+		o.output.Stderr = stderr
+	}
+}
+
+// WithLineHandler 返回一个 Option，每输出一行回调一次 fn, Line.Host 为 Connection.Host
+func WithLineHandler(fn func(Line)) Option {
+	return func(o *RunOptions) {
+		o.output.OnLine = fn
+	}
+}
+
+// WithTagHost 返回一个 Option，写入 WithOutputWriters 设置的 Writer 时每行加上 "[host] " 前缀
+func WithTagHost(tag bool) Option {
+	return func(o *RunOptions) {
+		o.output.TagHost = tag
+	}
+}
+
+// WithStripANSI 返回一个 Option，用于去掉输出中的 ANSI 转义序列
+func WithStripANSI(strip bool) Option {
+	return func(o *RunOptions) {
+		o.output.StripANSI = strip
+	}
+}
+
+// WithMaxOutput 返回一个 Option，Result 中的标准输出和标准错误各自最多保留 max 字节
+func WithMaxOutput(max int) Option {
+	return func(o *RunOptions) {
+		o.output.MaxBytes = max
+	}
+}
+
+// WithEncoding 返回一个 Option，将远程命令的输出从 enc 编码转换为 UTF-8, 转换失败时返回 *gocmd.DecodeError
+// 没有设置时, 如果通过 WithLocale 设置了非 UTF-8 的 locale, 则使用其字符集
+// 只作用于 Result 和 WithOutput/WithOutputWriters/WithLineHandler 的输出, 不作用于 WithHide(false) 时直接输出到终端的内容
+func WithEncoding(enc Encoding) Option {
+	return func(o *RunOptions) {
+		o.output.Encoding = enc
+	}
+}
+
+// WithRetry 返回一个 Option，执行失败后按策略 p 重试
+// p.RetryIf 为 nil 时, 输出中包含 gocmd.TransientPatterns 或提交命令前 ssh 会话异常(见 RetryBeforeStart)时重试
+// 命令提交后连接中断时执行状态未知, 默认不重试, 避免重复执行非幂等的命令
+func WithRetry(p RetryPolicy) Option {
+	return func(o *RunOptions) {
+		if p.RetryIf == nil {
+			p.RetryIf = gocmd.RetryAny(gocmd.RetryOnTransient, RetryBeforeStart)
+		}
+		o.retry = &p
+	}
+}
+
+// WithPty 返回一个 Option，用于设置是否申请伪终端, 默认申请
+// 伪终端会将标准错误合并到标准输出, 并可能转换输出中的字符, 需要精确的输出(如计算 md5)时设置为 false. 不申请时 sudo 可能无法输入密码
+func WithPty(pty bool) Option {
+	return func(o *RunOptions) {
+		o.noPty = !pty
+	}
+}
+
+// WithReadOnly 返回一个 Option，标记命令为只读(如查询状态、计算 md5), 设置了 Audit 时不演练也不记录, 演练模式下仍然执行
+func WithReadOnly(readOnly bool) Option {
+	return func(o *RunOptions) {
+		o.readOnly = readOnly
+	}
+}
+
 // WithSudoUser 只用于 sudo 函数
 func WithSudoUser(user string) Option {
 	return func(o *RunOptions) {
@@ -82,103 +181,331 @@ func WithSudoPattern(pattern string) Option {
 //}
 
 type Connection struct {
-	Host         string
-	Port         uint16
-	User         string
-	Password     string
-	KeyFile      string
-	Timeout      int64
-	auth         []ssh.AuthMethod
-	clientConfig *ssh.ClientConfig
-	sshClient    *ssh.Client
+	Host           string
+	Port           uint16
+	User           string
+	Password       string
+	KeyFile        string
+	KeyFiles       []string // 除 KeyFile 外的其他私钥文件, 不可用时跳过
+	Passphrase     string   // 加密私钥的密码
+	UseAgent       bool     // 是否使用 SSH_AUTH_SOCK 指向的 ssh-agent 中的密钥
+	Timeout        int64
+	KeepAlive      int64         // 发送 keepalive 探测的间隔(秒), 为 0 时使用 DefaultKeepAlive, 小于 0 时不发送
+	HostKeyPolicy  HostKeyPolicy // 主机公钥校验策略, 默认严格校验(strict), 主机公钥必须已存在于 known_hosts 中
+	KnownHostsFile string        // known_hosts 文件路径, 为空时使用 ~/.ssh/known_hosts
+	JumpHosts      []JumpHost    // 跳板机, 按顺序依次连接, 最后经由最后一台跳板机连接 Host
+	Audit          *gocmd.Audit  // 演练和审计设置, 作用于所有命令和修改文件的操作(见 change), 为 nil 时直接执行, 不记录
+
+	// 当前 ssh 连接上的 sftp 客户端, 断线重连后由下一次调用的 Connection 方法(或 Reconnect)更新
+	// 直接调用其方法不经过演练和审计, 也不会自动重连
 	*sftp.Client
+
+	keyboardInteractive ssh.KeyboardInteractiveChallenge
+
+	shared *sshConn    // ssh 连接和 sftp 客户端, 复用连接缓存时与其他 Connection 共享
+	closed atomic.Bool // 已经调用 Close, 每个 Connection 只释放一次对 shared 的引用
+	reuse  bool        // 使用连接缓存
+
+	factsMu sync.Mutex
+	facts   *Facts // Facts 的缓存
+
+	tunnelMu sync.Mutex
+	tunnels  map[*Tunnel]struct{} // 未关闭的端口转发, Close 时一起关闭
 }
 
-func NewConnection(host string, port uint16, user string, password string, keyfile string, timeout int64) (*Connection, error) {
+// ConnOption 是一个函数类型，用于在建立连接前修改 Connection
+type ConnOption func(*Connection)
+
+// WithHostKeyPolicy 返回一个 ConnOption，用于设置主机公钥校验策略, 默认为 HostKeyStrict
+// 设置为 HostKeyAcceptNew 时, 首次连接的主机公钥会被追加写入 known_hosts 文件(KnownHostsFile, 默认 ~/.ssh/known_hosts)
+func WithHostKeyPolicy(policy HostKeyPolicy) ConnOption {
+	return func(c *Connection) {
+		c.HostKeyPolicy = policy
+	}
+}
+
+// WithKnownHostsFile 返回一个 ConnOption，用于指定 known_hosts 文件
+func WithKnownHostsFile(file string) ConnOption {
+	return func(c *Connection) {
+		c.KnownHostsFile = file
+	}
+}
+
+// WithJumpHosts 返回一个 ConnOption，用于设置跳板机(ProxyJump), 可以设置多个, 按顺序连接
+func WithJumpHosts(hops ...JumpHost) ConnOption {
+	return func(c *Connection) {
+		c.JumpHosts = append(c.JumpHosts, hops...)
+	}
+}
+
+// WithKeepAlive 返回一个 ConnOption，用于设置发送 keepalive 探测的间隔(秒), 小于 0 时不发送
+func WithKeepAlive(seconds int64) ConnOption {
+	return func(c *Connection) {
+		c.KeepAlive = seconds
+	}
+}
+
+// WithReuse 返回一个 ConnOption，用于设置是否复用连接缓存中的健康连接, 默认不复用
+// 只有目标机器、跳板机、认证信息(密码、私钥内容、私钥密码)和连接选项都相同时才会复用, 见 poolKey
+// 复用时各调用方得到不同的 *Connection, 共享同一条 ssh 连接. 各调用方都需要调用 Close, 最后一个调用 Close 时才真正关闭连接
+func WithReuse(reuse bool) ConnOption {
+	return func(c *Connection) {
+		c.reuse = reuse
+	}
+}
+
+// WithAudit 返回一个 ConnOption，用于设置演练和审计. 设置后连接不放入连接缓存, 避免影响复用同一连接的其他调用方
+func WithAudit(audit *gocmd.Audit) ConnOption {
+	return func(c *Connection) {
+		c.Audit = audit
+	}
+}
+
+// NewConnection 建立 ssh 连接. 设置 WithReuse(true) 时复用连接缓存中的健康连接
+func NewConnection(host string, port uint16, user string, password string, keyfile string, timeout int64, opts ...ConnOption) (*Connection, error) {
 	var err error
 	conn := &Connection{Host: host, Port: port, User: user, Password: password, KeyFile: keyfile, Timeout: timeout}
 
+	for _, opt := range opts {
+		opt(conn)
+	}
+
 	if conn.Port == 0 {
 		conn.Port = 22
 	}
 
-	conn.auth = make([]ssh.AuthMethod, 0)
+	if conn.KeepAlive == 0 {
+		conn.KeepAlive = DefaultKeepAlive
+	}
 
-	if password != "" {
-		auth := ssh.Password(password)
-		conn.auth = append(conn.auth, auth)
+	// 演练和审计只作用于当前调用方, 自定义的 keyboard-interactive 应答无法比较, 都不复用
+	if conn.Audit != nil || conn.keyboardInteractive != nil {
+		conn.reuse = false
 	}
 
-	if keyfile != "" {
-		key, err := os.ReadFile(keyfile)
-		if err != nil {
-			return nil, err
-		}
-		signer, err := ssh.ParsePrivateKey(key)
-		if err != nil {
-			return nil, err
+	var key string
+	if conn.reuse {
+		key = conn.poolKey()
+		if conn.shared = pool.get(key); conn.shared != nil {
+			if err = conn.ensureConnected(); err == nil {
+				return conn, nil
+			}
+			// 缓存中的连接已断开且重连失败, 从缓存中移除, 重新建立连接
+			pool.remove(conn.shared)
+			_ = conn.shared.release()
 		}
-		conn.auth = append(conn.auth, ssh.PublicKeys(signer))
 	}
 
-	conn.clientConfig = &ssh.ClientConfig{
-		User:            user,
-		Auth:            conn.auth,
-		HostKeyCallback: ssh.HostKeyCallback(func(hostname string, remote net.Addr, key ssh.PublicKey) error { return nil }),
-		Timeout:         time.Duration(timeout) * time.Second,
-	}
+	s := &sshConn{refs: 1, done: make(chan struct{})}
+	conn.shared = s
 
-	address := fmt.Sprintf("%s:%d", conn.Host, conn.Port)
+	if err = conn.connectAgent(); err != nil {
+		return nil, err
+	}
 
-	if conn.sshClient, err = ssh.Dial("tcp", address, conn.clientConfig); err != nil {
+	if s.clientConfig, err = conn.newClientConfig(conn.Host, conn.Port, conn.User, conn.Password, conn.KeyFile, conn.KeyFiles); err != nil {
+		s.closeAgent()
 		return nil, err
 	}
 
-	if conn.Client, err = sftp.NewClient(conn.sshClient); err != nil {
+	if err = conn.connect(); err != nil {
+		s.closeAgent()
 		return nil, err
 	}
 
+	if conn.KeepAlive > 0 {
+		go s.keepalive(time.Duration(conn.KeepAlive)*time.Second, time.Duration(conn.Timeout)*time.Second)
+	}
+
+	if conn.reuse {
+		if cached, ok := pool.put(key, s); !ok {
+			// 并发建立了相同的连接, 使用缓存中已有的连接
+			_ = s.close()
+			conn.shared = cached
+			cached.mu.Lock()
+			conn.syncClient()
+			cached.mu.Unlock()
+		}
+	}
 	return conn, nil
 }
 
+// String 返回 user@host:port
+func (conn *Connection) String() string {
+	return conn.User + "@" + joinHostPort(conn.Host, conn.Port)
+}
+
+// connect 建立 ssh 连接和 sftp 客户端, 并在后台监测连接是否断开. 连接已共享时调用方需要持有 conn.shared.mu
+func (conn *Connection) connect() error {
+	s := conn.shared
+	sshClient, err := conn.dial()
+	if err != nil {
+		s.closeJumpClients()
+		return err
+	}
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		s.closeJumpClients()
+		return err
+	}
+
+	s.client, s.sftpClient = sshClient, client
+	conn.syncClient()
+	go s.watch(sshClient)
+	return nil
+}
+
+// Close 关闭连接上的端口转发, 以及 sftp 客户端、ssh 连接和经过的跳板机连接
+// 连接被多个调用方复用时, 只减少引用计数, 最后一个调用方 Close 时才真正关闭. 重复调用 Close 不会重复减少引用计数
+func (conn *Connection) Close() error {
+	if conn.shared == nil || !conn.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+
+	conn.closeTunnels()
+	return conn.shared.release()
+}
+
+// release 减少引用计数, 没有其他引用时关闭连接
+func (s *sshConn) release() error {
+	if !pool.release(s) {
+		return nil
+	}
+	return s.close()
+}
+
 func (conn *Connection) Run(cmd string, opts ...Option) (stdoutByte []byte, stderrByte []byte, err error) {
+	return conn.RunContext(context.Background(), cmd, opts...)
+}
+
+// RunContext 与 Run 相同, 但在 ctx 被取消或超时时, 向远程进程发送信号并关闭会话,
+// 返回 *TimeoutError, 其中包含已经捕获到的部分标准输出和标准错误
+func (conn *Connection) RunContext(ctx context.Context, cmd string, opts ...Option) (stdoutByte []byte, stderrByte []byte, err error) {
+	result, err := conn.ExecContext(ctx, cmd, opts...)
+	return result.Stdout, result.Stderr, err
+}
+
+// Exec 与 Run 相同, 返回包含退出码、信号、输出、执行时间等信息的 Result
+func (conn *Connection) Exec(cmd string, opts ...Option) (*Result, error) {
+	return conn.ExecContext(context.Background(), cmd, opts...)
+}
+
+// ExecContext 执行远程命令, 返回的错误可以通过 errors.As 区分:
+//   - *ExitError: 命令已执行, 退出码不为 0 或被信号终止
+//   - *TimeoutError: ctx 被取消或超时
+//   - *TransportError: ssh 会话异常, 命令的执行状态未知
+func (conn *Connection) ExecContext(ctx context.Context, cmd string, opts ...Option) (*Result, error) {
+	options := newRunOptions(opts)
+
+	script, err := options.script(cmd, false)
+	if err != nil {
+		return &Result{Host: conn.Host, Cmd: cmd, ExitCode: -1}, err
+	}
+
+	if options.shell != "" {
+		script = fmt.Sprintf("%s -c %s", strutil.Quote(options.shell), strutil.Quote(script))
+	}
+	return conn.exec(ctx, script, options)
+}
+
+// exec 执行已经组装好的完整命令, 设置了重试策略时按策略重试
+func (conn *Connection) exec(ctx context.Context, cmd string, options *RunOptions) (*Result, error) {
+	if options.retry == nil {
+		return conn.audit(ctx, cmd, options)
+	}
+	return options.retry.Do(ctx, func() (*Result, error) {
+		return conn.audit(ctx, cmd, options)
+	})
+}
+
+// audit 设置了 Audit 时演练或记录命令, 只读命令直接执行
+func (conn *Connection) audit(ctx context.Context, cmd string, options *RunOptions) (*Result, error) {
+	if conn.Audit == nil || options.readOnly {
+		return conn.run(ctx, cmd, options)
+	}
+
+	secrets := []string{conn.Password, conn.Passphrase, options.sudoPassword}
+	if conn.Audit.DryRun {
+		result := gocmd.DryRunResult(conn.Host, cmd)
+		return result, conn.Audit.RecordResult(result, conn.User, nil, secrets...)
+	}
+
+	result, err := conn.run(ctx, cmd, options)
+	return result, conn.Audit.RecordResult(result, conn.User, err, secrets...)
+}
+
+// change 所有修改远程文件(以及 Download 修改本地文件)的操作都经过此方法演练或记录, cmd 为记录的等价命令
+// 演练时不执行 fn. fn 内部使用传入的 sftp 客户端, 不再调用 Connection 上的修改方法, 避免重复记录
+func (conn *Connection) change(cmd string, fn func(client *sftp.Client) error) error {
+	return conn.Audit.Change(conn.Host, conn.User, cmd, func() error {
+		client, err := conn.sftp()
+		if err != nil {
+			return err
+		}
+		return fn(client)
+	}, conn.Password, conn.Passphrase)
+}
+
+// run 执行命令并扫描输出
+func (conn *Connection) run(ctx context.Context, cmd string, options *RunOptions) (*Result, error) {
+	var err error
 	var stdin io.WriteCloser
 	var stdouts io.Reader
 	var stdout io.Reader
-	var stderr = new(bytes.Buffer)
 
-	options := &RunOptions{
-		hide: false, // 默认显示输出
+	result := &Result{Host: conn.Host, Cmd: cmd, ExitCode: -1, StartTime: time.Now()}
+	defer func() {
+		if result.EndTime.IsZero() {
+			result.EndTime = time.Now()
+		}
+	}()
+
+	if err = ctx.Err(); err != nil {
+		return result, &TimeoutError{Result: result, Err: err}
 	}
 
-	for _, opt := range opts {
-		opt(options)
+	sshClient, err := conn.client()
+	if err != nil {
+		return result, err
 	}
 
-	cmd = fmt.Sprintf("PATH=$PATH:/usr/bin:/usr/sbin %s", cmd)
-	session, err := conn.sshClient.NewSession()
+	session, err := sshClient.NewSession()
 	if err != nil {
-		return stdoutByte, stderrByte, err
+		return result, &TransportError{Host: conn.Host, Err: err}
 	}
 	defer session.Close()
 
 	modes := ssh.TerminalModes{
-	        ssh.ONLCR:         0,      // 含义：禁用换行符转换
+		ssh.ONLCR:         0,      // 含义：禁用换行符转换
 		ssh.ECHO:          0,      // disable echoing, 含义：禁用输入回显
 		ssh.TTY_OP_ISPEED: 144000, // input speed = 14.4kbaud
 		ssh.TTY_OP_OSPEED: 144000, // output speed = 14.4kbaud
 	}
 
-	if err = session.RequestPty("xterm", 80, 40, modes); err != nil {
-		return stdoutByte, stderrByte, err
+	if !options.noPty {
+		if err = session.RequestPty("xterm", 80, 40, modes); err != nil {
+			return result, &TransportError{Host: conn.Host, Err: err}
+		}
 	}
 	if stdin, err = session.StdinPipe(); err != nil {
-		return stdoutByte, stderrByte, err
+		return result, &TransportError{Host: conn.Host, Err: err}
 	}
 	if stdout, err = session.StdoutPipe(); err != nil {
-		return stdoutByte, stderrByte, err
+		return result, &TransportError{Host: conn.Host, Err: err}
 	}
-	session.Stderr = stderr
+
+	ws, err := gocmd.NewWatcherSet(stdin, options.Watchers)
+	if err != nil {
+		return result, err
+	}
+	output := options.output
+	if output.Encoding == gocmd.EncodingUTF8 {
+		output.Encoding = gocmd.EncodingFromLocale(options.locale)
+	}
+	capture := output.NewCapture(conn.Host)
+	session.Stderr = ws.Writer(capture.Stderr, true)
 
 	// 👇 关键：用 TeeReader 同时写入 os.Stdout 和供 watchers 读取
 	if !options.hide {
@@ -187,28 +514,108 @@ func (conn *Connection) Run(cmd string, opts ...Option) (stdoutByte []byte, stde
 		stdouts = stdout
 	}
 
+	if err = session.Start(cmd); err != nil {
+		return result, &TransportError{Host: conn.Host, Err: err, Started: true}
+	}
+
 	var wg sync.WaitGroup
 	wg.Go(func() {
-		watchers(stdin, stdouts, &stdoutByte, options.Watchers)
+		ws.Watch(stdouts, capture.Stdout)
 	})
-	err = session.Run(cmd)
-	wg.Wait()
 
-	if err != nil {
-		return stdoutByte, stderr.Bytes(), err
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Wait()
+	}()
+
+	var decodeErr error
+	finish := func() {
+		capture.Close()
+		result.EndTime = time.Now()
+		result.Stdout, result.Stderr, decodeErr = capture.Bytes()
+		result.Truncated = capture.Truncated()
+	}
+
+	// stop 中断远程命令, 网络异常时会话可能无法正常结束, 最多等待 cancelWait 后直接返回已捕获的输出
+	stop := func() {
+		_ = session.Signal(options.cancelSignal)
+		_ = session.Close()
+		select {
+		case <-done:
+			wg.Wait()
+		case <-time.After(cancelWait):
+		}
+		finish()
+	}
+
+	select {
+	case err = <-done:
+		wg.Wait()
+		finish()
+		// 匹配到 Sentinel 时命令可能恰好已经结束
+		select {
+		case serr := <-ws.Abort():
+			serr.Result = result
+			return result, serr
+		default:
+		}
+		if err = conn.exitError(result, err); err != nil {
+			return result, err
+		}
+		// 命令执行成功但输出转换失败时返回转换的错误
+		return result, decodeErr
+	case serr := <-ws.Abort():
+		stop()
+		serr.Result = result
+		return result, serr
+	case <-ctx.Done():
+		stop()
+		return result, &TimeoutError{Result: result, Err: ctx.Err()}
 	}
-	return stdoutByte, stderr.Bytes(), nil
 }
 
-func (conn *Connection) Sudo(cmd string, opts ...Option) (stdoutByte []byte, stderrByte []byte, err error) {
-	options := &RunOptions{
-		hide: false, // 默认显示输出
+// exitError 根据 session.Wait 返回的错误填充退出码和信号, 并转换为对应的错误类型
+func (conn *Connection) exitError(result *Result, err error) error {
+	if err == nil {
+		result.ExitCode = 0
+		return nil
 	}
 
-	for _, opt := range opts {
-		opt(options)
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		result.ExitCode = exitErr.ExitStatus()
+		result.Signal = exitErr.Signal()
+		if result.Signal != "" {
+			result.ExitCode = -1
+		}
+		return &ExitError{Result: result}
 	}
 
+	// 包括 *ssh.ExitMissingError: 远程没有返回退出状态, 通常是连接中断
+	return &TransportError{Host: conn.Host, Err: err, Started: true}
+}
+
+func (conn *Connection) Sudo(cmd string, opts ...Option) (stdoutByte []byte, stderrByte []byte, err error) {
+	return conn.SudoContext(context.Background(), cmd, opts...)
+}
+
+// SudoContext 与 Sudo 相同, 取消和超时的处理方式见 RunContext
+func (conn *Connection) SudoContext(ctx context.Context, cmd string, opts ...Option) (stdoutByte []byte, stderrByte []byte, err error) {
+	result, err := conn.SudoExecContext(ctx, cmd, opts...)
+	return result.Stdout, result.Stderr, err
+}
+
+// SudoExec 与 Sudo 相同, 返回 Result
+func (conn *Connection) SudoExec(cmd string, opts ...Option) (*Result, error) {
+	return conn.SudoExecContext(context.Background(), cmd, opts...)
+}
+
+// SudoExecContext 以 sudo 执行远程命令, 返回值同 ExecContext
+// 要执行的命令整体作为一个参数传给 sudo 启动的 shell, 命令中的引号和 $ 在目标用户的 shell 中解析
+// sudo 密码错误时返回 *SentinelError, 没有 sudo 密码(包括 Connection.Password)且 sudo 需要密码时返回 *PasswordRequiredError
+func (conn *Connection) SudoExecContext(ctx context.Context, cmd string, opts ...Option) (*Result, error) {
+	options := newRunOptions(opts)
+
 	if options.sudoUser == "" {
 		options.sudoUser = "root"
 	}
@@ -218,14 +625,30 @@ func (conn *Connection) Sudo(cmd string, opts ...Option) (stdoutByte []byte, std
 	}
 
 	if options.sudoPattern == "" {
-		options.sudoPattern = "[sudo] password: "
+		options.sudoPattern = gocmd.DefaultSudoPattern
 	}
 
-	cmd = fmt.Sprintf("sudo -S -p '%s' -H -u %s /bin/bash -c \"cd; %s\"", options.sudoPattern, options.sudoUser, cmd)
-	watcher := Watcher{Pattern: options.sudoPattern, Response: options.sudoPassword}
-	options.Watchers = append(options.Watchers, watcher)
+	if options.shell == "" {
+		options.shell = "/bin/bash"
+	}
 
-	return conn.Run(cmd, WithHide(options.hide), WithWatchers(options.Watchers...))
+	script, err := options.script(cmd, true)
+	if err != nil {
+		return &Result{Host: conn.Host, Cmd: cmd, ExitCode: -1}, err
+	}
+
+	if options.sudoPassword == "" {
+		// 没有密码时不等待输入, sudo 需要密码时直接失败并返回 PasswordRequiredError
+		cmd = fmt.Sprintf("sudo -n -H -u %s %s -c %s", strutil.Quote(options.sudoUser), strutil.Quote(options.shell), strutil.Quote(script))
+	} else {
+		cmd = fmt.Sprintf("sudo -S -p %s -H -u %s %s -c %s", strutil.Quote(options.sudoPattern), strutil.Quote(options.sudoUser), strutil.Quote(options.shell), strutil.Quote(script))
+		// 密码错误时 sudo 会再次提示输入, 只应答一次, 并以 "Sorry, try again" 判定失败, 避免反复应答错误的密码
+		watcher := Watcher{Pattern: options.sudoPattern, Response: options.sudoPassword, Sentinel: "Sorry, try again", Once: true, Stderr: true}
+		options.Watchers = append(options.Watchers, watcher)
+	}
+
+	result, err := conn.exec(ctx, cmd, options)
+	return result, gocmd.SudoPasswordRequiredError(result, err)
 }
 
 // Scp 实现本地文件/目录上传到远程服务器
@@ -233,7 +656,16 @@ func (conn *Connection) Sudo(cmd string, opts ...Option) (stdoutByte []byte, std
 // 如果source是文件, target是目录, target 需要加文件名: path.Join(target, path.Base(source))
 // 如果source是目录, target是文件, 报错,目标不是一个路径
 // 如果source是目录, target是目录, 直接遍历copy, 如果target不存在,则自动创建。 如果存在,则创建下一级与 path.Join(target, path.Base(source)) 同名的目录(如果存在, 则报错)
-func (conn *Connection) Scp(source, target string) error {
+// 传输选项见 TransferOption, 如进度回调、断点续传、校验、保留权限和修改时间、限速
+// 设置了 Audit 时以 "scp <source> <host>:<target>" 记录
+func (conn *Connection) Scp(source, target string, opts ...TransferOption) error {
+	cmd := fmt.Sprintf("scp %s %s:%s", source, conn.Host, target)
+	return conn.change(cmd, func(client *sftp.Client) error {
+		return conn.scp(client, source, target, opts...)
+	})
+}
+
+func (conn *Connection) scp(client *sftp.Client, source, target string, opts ...TransferOption) error {
 	// 通过ssh协议传输文件的目标机器全都是linux系统, 所以将目标路径强制转换为Linux格式
 	target = filepath.ToSlash(target)
 
@@ -251,11 +683,11 @@ func (conn *Connection) Scp(source, target string) error {
 		if conn.IsDir(target) {
 			target = filepath.ToSlash(path.Join(target, path.Base(filepath.ToSlash(source))))
 		}
-		return conn.Copy(source, target)
+		return conn.copy(client, source, target, opts...)
 	}
 
 	if !conn.IsExists(target) {
-		return conn.LoopCopy(source, target)
+		return conn.loopCopy(client, source, target, opts...)
 	}
 
 	if !conn.IsDir(target) {
@@ -263,48 +695,70 @@ func (conn *Connection) Scp(source, target string) error {
 	}
 
 	target = filepath.ToSlash(path.Join(target, path.Base(filepath.ToSlash(source))))
-	return conn.LoopCopy(source, target)
+	return conn.loopCopy(client, source, target, opts...)
 }
 
-func (conn *Connection) singleCopy(source, target string, path string, info os.FileInfo) error {
+func (conn *Connection) singleCopy(client *sftp.Client, source, target string, path string, info os.FileInfo, opts ...TransferOption) error {
 	relative, err := filepath.Rel(source, path)
 	if err != nil {
 		fmt.Println("获取相对路径失败: ", err)
 	}
 
 	if info.IsDir() {
-		if err := conn.MkdirAll(filepath.ToSlash(filepath.Join(target, relative))); err != nil {
+		if err := client.MkdirAll(filepath.ToSlash(filepath.Join(target, relative))); err != nil {
 			return err
 		}
+		if newTransferOptions(opts).preserve {
+			if err := client.Chmod(filepath.ToSlash(filepath.Join(target, relative)), info.Mode().Perm()); err != nil {
+				return err
+			}
+		}
 	} else {
 		dir, _ := filepath.Split(filepath.Join(target, relative))
-		if err := conn.MkdirAll(filepath.ToSlash(dir)); err != nil {
+		if err := client.MkdirAll(filepath.ToSlash(dir)); err != nil {
 			return err
 		}
-		if err := conn.Copy(path, filepath.ToSlash(filepath.Join(target, relative))); err != nil {
+		if err := conn.copy(client, path, filepath.ToSlash(filepath.Join(target, relative)), opts...); err != nil {
 			return err
 		}
 	}
 	return err
 }
 
-func (conn *Connection) LoopCopy(source, target string) error {
-	if err := filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
+// LoopCopy 递归上传本地目录 source 下的所有文件到远程目录 target
+// 设置了 Audit 时以 "scp -r <source> <host>:<target>" 记录
+func (conn *Connection) LoopCopy(source, target string, opts ...TransferOption) error {
+	cmd := fmt.Sprintf("scp -r %s %s:%s", source, conn.Host, target)
+	return conn.change(cmd, func(client *sftp.Client) error {
+		return conn.loopCopy(client, source, target, opts...)
+	})
+}
+
+func (conn *Connection) loopCopy(client *sftp.Client, source, target string, opts ...TransferOption) error {
+	return filepath.Walk(source, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		return conn.singleCopy(source, target, path, info)
-	}); err != nil {
-		return err
-	}
-	return nil
+		return conn.singleCopy(client, source, target, path, info, opts...)
+	})
 }
 
-func (conn *Connection) Copy(source, target string) error {
+// Copy 上传本地文件 source 到远程文件 target
+// 设置了 Audit 时以 "scp <source> <host>:<target>" 记录
+func (conn *Connection) Copy(source, target string, opts ...TransferOption) error {
+	cmd := fmt.Sprintf("scp %s %s:%s", source, conn.Host, target)
+	return conn.change(cmd, func(client *sftp.Client) error {
+		return conn.copy(client, source, target, opts...)
+	})
+}
+
+func (conn *Connection) copy(client *sftp.Client, source, target string, opts ...TransferOption) error {
 	var sf *os.File
 	var df *sftp.File
 	var err error
 
+	options := newTransferOptions(opts)
+
 	// 通过ssh协议传输文件的目标机器全都是linux系统, 所以将目标路径强制转换为Linux格式
 	target = filepath.ToSlash(target)
 
@@ -313,14 +767,36 @@ func (conn *Connection) Copy(source, target string) error {
 	}
 	defer sf.Close()
 
-	if df, err = conn.Create(target); err != nil {
+	info, err := sf.Stat()
+	if err != nil {
 		return err
 	}
-	defer df.Close()
-	if _, err = df.ReadFrom(sf); err != nil {
+
+	offset := conn.resumeOffset(client, source, target, info.Size(), options)
+	if offset > 0 {
+		if df, err = client.OpenFile(target, os.O_WRONLY); err != nil {
+			return err
+		}
+		if _, err = sf.Seek(offset, io.SeekStart); err == nil {
+			_, err = df.Seek(offset, io.SeekStart)
+		}
+		if err != nil {
+			df.Close()
+			return err
+		}
+	} else if df, err = client.Create(target); err != nil {
 		return err
 	}
-	return nil
+
+	if _, err = df.ReadFrom(options.wrap(sf, source, offset, info.Size())); err != nil {
+		df.Close()
+		return err
+	}
+	if err = df.Close(); err != nil {
+		return err
+	}
+
+	return conn.finishTransfer(client, source, target, info, options)
 }
 
 func (conn *Connection) IsExists(path string) bool {
@@ -0,0 +1,139 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 16:40:18
+ */
+
+package gossh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// JumpHost ssh 跳板机(ProxyJump)
+// 各字段为空时使用目标机器的配置: Port 默认 22, User 默认与 Connection 相同,
+// Password 和 KeyFile 都为空时使用 Connection 的 Password, KeyFile 和 KeyFiles. ssh-agent 对所有跳板机都生效
+type JumpHost struct {
+	Host     string
+	Port     uint16
+	User     string
+	Password string
+	KeyFile  string
+}
+
+// newClientConfig 生成连接指定机器使用的 ssh 客户端配置
+func (conn *Connection) newClientConfig(host string, port uint16, user, password, keyfile string, keyfiles []string) (*ssh.ClientConfig, error) {
+	auth, err := conn.authMethods(password, keyfile, keyfiles)
+	if err != nil {
+		return nil, err
+	}
+
+	hostKeyCallback, err := conn.hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         time.Duration(conn.Timeout) * time.Second,
+	}
+
+	if conn.HostKeyPolicy != HostKeyInsecure {
+		config.HostKeyAlgorithms = knownHostAlgorithms(conn.KnownHostsFile, joinHostPort(host, port))
+	}
+	return config, nil
+}
+
+// dial 连接目标机器, 如果配置了跳板机, 则依次经过每一台跳板机建立连接
+func (conn *Connection) dial() (*ssh.Client, error) {
+	address := joinHostPort(conn.Host, conn.Port)
+	if len(conn.JumpHosts) == 0 {
+		return ssh.Dial("tcp", address, conn.shared.clientConfig)
+	}
+
+	var via *ssh.Client
+	for _, hop := range conn.jumpHosts() {
+		var keyfiles []string
+		if hop.Password == "" && hop.KeyFile == "" {
+			hop.Password, hop.KeyFile, keyfiles = conn.Password, conn.KeyFile, conn.KeyFiles
+		}
+
+		config, err := conn.newClientConfig(hop.Host, hop.Port, hop.User, hop.Password, hop.KeyFile, keyfiles)
+		if err != nil {
+			return nil, fmt.Errorf("跳板机 %s: %w", hop.Host, err)
+		}
+
+		hopAddress := joinHostPort(hop.Host, hop.Port)
+		if via == nil {
+			via, err = ssh.Dial("tcp", hopAddress, config)
+		} else {
+			via, err = conn.dialVia(via, hopAddress, config)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("连接跳板机 %s 失败: %w", hopAddress, err)
+		}
+		conn.shared.jumpClients = append(conn.shared.jumpClients, via)
+	}
+
+	return conn.dialVia(via, address, conn.shared.clientConfig)
+}
+
+// jumpHosts 返回填充了默认端口和用户的跳板机
+func (conn *Connection) jumpHosts() []JumpHost {
+	hops := slices.Clone(conn.JumpHosts)
+	for i := range hops {
+		if hops[i].Port == 0 {
+			hops[i].Port = 22
+		}
+		if hops[i].User == "" {
+			hops[i].User = conn.User
+		}
+	}
+	return hops
+}
+
+// dialVia 通过已经建立的 ssh 连接转发 tcp, 再在其上建立新的 ssh 连接
+func (conn *Connection) dialVia(via *ssh.Client, address string, config *ssh.ClientConfig) (*ssh.Client, error) {
+	ctx := context.Background()
+	if conn.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(conn.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	nc, err := via.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(nc, address, config)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// keyFiles 返回 KeyFile 和 KeyFiles 中的所有私钥文件
+func (conn *Connection) keyFiles() []string {
+	return append([]string{conn.KeyFile}, conn.KeyFiles...)
+}
+
+// closeJumpClients 从最后一台跳板机开始, 依次关闭跳板机连接
+func (s *sshConn) closeJumpClients() {
+	for i := len(s.jumpClients) - 1; i >= 0; i-- {
+		s.jumpClients[i].Close()
+	}
+	s.jumpClients = nil
+}
+
+func joinHostPort(host string, port uint16) string {
+	return net.JoinHostPort(host, fmt.Sprintf("%d", port))
+}
@@ -0,0 +1,74 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-20 15:06:52
+ */
+
+package gossh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lsne/goutils/utils/gossh/sshtest"
+)
+
+func TestServerJumpHosts(t *testing.T) {
+	jump1, jump2, target := sshtest.NewServer(t), sshtest.NewServer(t), sshtest.NewServer(t)
+
+	// 跳板机和目标机器的主机公钥都从同一个 known_hosts 文件中校验
+	var knownHosts []byte
+	for _, s := range []*sshtest.Server{jump1, jump2, target} {
+		data, err := os.ReadFile(s.KnownHostsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		knownHosts = append(knownHosts, data...)
+	}
+	knownHostsFile := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(knownHostsFile, knownHosts, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	connect := func(hops ...JumpHost) (*Connection, error) {
+		return NewConnection(target.Host, target.Port, target.User, target.Password, "", 5,
+			WithKnownHostsFile(knownHostsFile), WithHostKeyPolicy(HostKeyStrict), WithReuse(false), WithJumpHosts(hops...))
+	}
+
+	// 第一台跳板机使用私钥, 第二台跳板机的认证信息为空时使用目标机器的密码
+	conn, err := connect(
+		JumpHost{Host: jump1.Host, Port: jump1.Port, User: jump1.User, KeyFile: jump1.KeyFile},
+		JumpHost{Host: jump2.Host, Port: jump2.Port},
+	)
+	if err != nil {
+		t.Fatalf("经由跳板机连接失败: %v", err)
+	}
+	defer conn.Close()
+	if result, err := conn.Exec("echo ok", WithHide(true)); err != nil || string(result.Stdout) != "ok\n" {
+		t.Fatalf("经由跳板机执行命令失败: %q, %v", result.Stdout, err)
+	}
+	if len(conn.shared.jumpClients) != 2 {
+		t.Errorf("应建立 2 个跳板机连接, 实际: %d", len(conn.shared.jumpClients))
+	}
+
+	// 断线后重连同样经过跳板机
+	target.CloseConnections()
+	waitDead(t, conn, 5*time.Second)
+	if _, err := conn.Exec("true", WithHide(true)); err != nil {
+		t.Fatalf("经由跳板机重连失败: %v", err)
+	}
+	if len(conn.shared.jumpClients) != 2 {
+		t.Errorf("重连后应重新建立 2 个跳板机连接, 实际: %d", len(conn.shared.jumpClients))
+	}
+
+	if c, err := connect(JumpHost{Host: jump1.Host, Port: jump1.Port, Password: "wrong"}); err == nil {
+		c.Close()
+		t.Error("跳板机密码错误时应连接失败")
+	} else if !strings.Contains(err.Error(), "跳板机") {
+		t.Errorf("错误信息中应指明跳板机: %v", err)
+	}
+}
@@ -0,0 +1,259 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 21:03:42
+ */
+
+package gossh
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/lsne/goutils/utils/fileutil"
+	"github.com/pkg/sftp"
+)
+
+// Download 实现远程服务器文件/目录下载到本地, 规则与 Scp 相同, 只是方向相反
+// 如果source是文件, target是文件, 直接调用 DownloadFile
+// 如果source是文件, target是目录, target 需要加文件名: filepath.Join(target, path.Base(source))
+// 如果source是目录, target是文件, 报错,目标不是一个路径
+// 如果source是目录, target是目录, 直接遍历下载, 如果target不存在,则自动创建。 如果存在,则创建下一级与 filepath.Join(target, path.Base(source)) 同名的目录
+// 传输选项见 TransferOption, 断点续传时比较本地已存在的文件与远程文件相同长度部分的 md5
+// 设置了 Audit 时以 "download <host>:<source> <target>" 记录
+func (conn *Connection) Download(source, target string, opts ...TransferOption) error {
+	cmd := fmt.Sprintf("download %s:%s %s", conn.Host, source, target)
+	return conn.change(cmd, func(client *sftp.Client) error {
+		return conn.download(client, source, target, opts...)
+	})
+}
+
+func (conn *Connection) download(client *sftp.Client, source, target string, opts ...TransferOption) error {
+	// 远程机器全都是linux系统, 所以将源路径强制转换为Linux格式
+	source = filepath.ToSlash(source)
+
+	if source == "" {
+		return fmt.Errorf("源文件不能为空")
+	}
+	if target == "" {
+		return fmt.Errorf("目标路径不能为空")
+	}
+
+	if !conn.IsExists(source) {
+		return fmt.Errorf("在机器: %s 上, 文件 %s 不存在", conn.Host, source)
+	}
+
+	if !conn.IsDir(source) {
+		if fileutil.IsDir(target) {
+			target = filepath.Join(target, path.Base(source))
+		}
+		return conn.downloadFile(client, source, target, opts...)
+	}
+
+	if !fileutil.IsExists(target) {
+		return conn.loopDownload(client, source, target, opts...)
+	}
+
+	if !fileutil.IsDir(target) {
+		return fmt.Errorf("本地已经存在同名文件: %s", target)
+	}
+
+	target = filepath.Join(target, path.Base(source))
+	return conn.loopDownload(client, source, target, opts...)
+}
+
+// LoopDownload 递归下载远程目录 source 下的所有文件到本地目录 target
+// 设置了 Audit 时以 "download -r <host>:<source> <target>" 记录
+func (conn *Connection) LoopDownload(source, target string, opts ...TransferOption) error {
+	cmd := fmt.Sprintf("download -r %s:%s %s", conn.Host, source, target)
+	return conn.change(cmd, func(client *sftp.Client) error {
+		return conn.loopDownload(client, source, target, opts...)
+	})
+}
+
+func (conn *Connection) loopDownload(client *sftp.Client, source, target string, opts ...TransferOption) error {
+	preserve := newTransferOptions(opts).preserve
+	walker := client.Walk(source)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+
+		relative, err := filepath.Rel(source, walker.Path())
+		if err != nil {
+			return fmt.Errorf("获取相对路径失败: %v", err)
+		}
+		local := filepath.Join(target, filepath.FromSlash(relative))
+
+		info := walker.Stat()
+		if info.IsDir() {
+			if err := os.MkdirAll(local, 0755); err != nil {
+				return err
+			}
+			if preserve {
+				if err := os.Chmod(local, info.Mode().Perm()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+			return err
+		}
+		if err := conn.downloadFile(client, walker.Path(), local, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DownloadFile 下载远程文件 source 到本地文件 target
+// 设置了 Audit 时以 "download <host>:<source> <target>" 记录
+func (conn *Connection) DownloadFile(source, target string, opts ...TransferOption) error {
+	cmd := fmt.Sprintf("download %s:%s %s", conn.Host, source, target)
+	return conn.change(cmd, func(client *sftp.Client) error {
+		return conn.downloadFile(client, source, target, opts...)
+	})
+}
+
+func (conn *Connection) downloadFile(client *sftp.Client, source, target string, opts ...TransferOption) error {
+	var sf *sftp.File
+	var df *os.File
+	var err error
+
+	options := newTransferOptions(opts)
+	source = filepath.ToSlash(source)
+
+	if sf, err = client.Open(source); err != nil {
+		return err
+	}
+	defer sf.Close()
+
+	info, err := sf.Stat()
+	if err != nil {
+		return err
+	}
+
+	offset := conn.downloadResumeOffset(source, target, info.Size(), options)
+
+	if offset > 0 {
+		if df, err = os.OpenFile(target, os.O_WRONLY, 0644); err != nil {
+			return err
+		}
+		if _, err = sf.Seek(offset, io.SeekStart); err == nil {
+			_, err = df.Seek(offset, io.SeekStart)
+		}
+		if err != nil {
+			df.Close()
+			return err
+		}
+	} else if df, err = os.Create(target); err != nil {
+		return err
+	}
+
+	if options.ctx == nil && options.progress == nil && options.rateLimit <= 0 {
+		_, err = sf.WriteTo(df)
+	} else {
+		_, err = io.Copy(df, options.wrap(sf, source, offset, info.Size()))
+	}
+	if err != nil {
+		df.Close()
+		return err
+	}
+	if err = df.Close(); err != nil {
+		return err
+	}
+
+	if options.verify {
+		if err := conn.verifyMD5(target, source); err != nil {
+			return err
+		}
+	}
+
+	if options.preserve {
+		if err := os.Chmod(target, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if err := os.Chtimes(target, info.ModTime(), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyTo 将当前机器上的文件 source 直接传输到 dst 机器的 target, 数据不落本地磁盘
+// 如果 target 是 dst 机器上已存在的目录, 则传输到 path.Join(target, path.Base(source))
+// 支持 WithProgress、WithVerify、WithPreserve、WithRateLimit, 不支持断点续传
+// 按 dst 的 Audit 以 "copy <host>:<source> <dst host>:<target>" 演练或记录
+func (conn *Connection) CopyTo(dst *Connection, source, target string, opts ...TransferOption) error {
+	cmd := fmt.Sprintf("copy %s:%s %s:%s", conn.Host, source, dst.Host, target)
+	return dst.change(cmd, func(client *sftp.Client) error {
+		return conn.copyTo(dst, client, source, target, opts...)
+	})
+}
+
+// copyTo client 为 dst 的 sftp 客户端
+func (conn *Connection) copyTo(dst *Connection, client *sftp.Client, source, target string, opts ...TransferOption) error {
+	var sf, df *sftp.File
+	var err error
+
+	options := newTransferOptions(opts)
+	source = filepath.ToSlash(source)
+	target = filepath.ToSlash(target)
+
+	if sf, err = conn.Open(source); err != nil {
+		return fmt.Errorf("在机器: %s 上, 打开文件(%s)失败: %v", conn.Host, source, err)
+	}
+	defer sf.Close()
+
+	info, err := sf.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fmt.Errorf("在机器: %s 上, %s 是一个目录", conn.Host, source)
+	}
+
+	if dst.IsDir(target) {
+		target = path.Join(target, path.Base(source))
+	}
+
+	if df, err = client.Create(target); err != nil {
+		return fmt.Errorf("在机器: %s 上, 创建文件(%s)失败: %v", dst.Host, target, err)
+	}
+
+	if _, err = df.ReadFrom(options.wrap(sf, source, 0, info.Size())); err != nil {
+		df.Close()
+		return err
+	}
+	if err = df.Close(); err != nil {
+		return err
+	}
+
+	if options.verify {
+		srcSum, err := conn.FileMD5(source)
+		if err != nil {
+			return err
+		}
+		dstSum, err := dst.FileMD5(target)
+		if err != nil {
+			return err
+		}
+		if srcSum != dstSum {
+			return fmt.Errorf("机器: %s 上的文件(%s)的 md5(%s)与机器: %s 上的文件(%s)的 md5(%s)不一致", dst.Host, target, dstSum, conn.Host, source, srcSum)
+		}
+	}
+
+	if options.preserve {
+		if err := client.Chmod(target, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if err := client.Chtimes(target, info.ModTime(), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-20 15:38:14
+ */
+
+package gossh
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lsne/goutils/utils/gossh/sshtest"
+)
+
+func TestServerDownload(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	if err := os.MkdirAll(filepath.Join(s.Dir, "conf", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	remote := filepath.Join(s.Dir, "conf", "my.cnf")
+	if err := os.WriteFile(remote, []byte("port=3306\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(remote, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, "conf", "sub", "b.cnf"), []byte("b=2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// 目标是已存在的目录时下载到其下的同名文件, 保留权限和修改时间
+	local := t.TempDir()
+	if err := conn.Download(remote, local, WithPreserve()); err != nil {
+		t.Fatalf("下载文件失败: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(local, "my.cnf"))
+	if err != nil || info.Mode().Perm() != 0600 || !info.ModTime().Equal(mtime) {
+		t.Errorf("下载的文件应保留权限和修改时间: %v, %v", info, err)
+	}
+
+	// 目标目录不存在时直接作为下载的目录, 已存在时下载到其下的同名目录
+	if err := conn.Download(filepath.Join(s.Dir, "conf"), filepath.Join(local, "backup")); err != nil {
+		t.Fatalf("下载目录失败: %v", err)
+	}
+	if err := conn.Download(filepath.Join(s.Dir, "conf"), filepath.Join(local, "backup")); err != nil {
+		t.Fatalf("下载目录失败: %v", err)
+	}
+	for _, name := range []string{"backup/sub/b.cnf", "backup/conf/sub/b.cnf"} {
+		if data, err := os.ReadFile(filepath.Join(local, name)); err != nil || string(data) != "b=2\n" {
+			t.Errorf("下载的文件 %s 不正确: %q, %v", name, data, err)
+		}
+	}
+
+	if err := conn.Download(filepath.Join(s.Dir, "conf"), filepath.Join(local, "my.cnf")); err == nil {
+		t.Error("本地已存在同名文件时下载目录应返回错误")
+	}
+	if err := conn.Download(filepath.Join(s.Dir, "missing"), local); err == nil {
+		t.Error("远程文件不存在时应返回错误")
+	}
+}
+
+func TestServerCopyTo(t *testing.T) {
+	src, dst := sshtest.NewServer(t), sshtest.NewServer(t)
+	srcConn := newTestConnection(t, src, src.Password, "")
+	dstConn := newTestConnection(t, dst, dst.Password, "")
+
+	source := filepath.Join(src.Dir, "data")
+	data := []byte("remote to remote\n")
+	if err := os.WriteFile(source, data, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	// 目标是已存在的目录时传输到其下的同名文件
+	var last Progress
+	if err := srcConn.CopyTo(dstConn, source, dst.Dir, WithVerify(), WithPreserve(), WithProgress(func(p Progress) { last = p })); err != nil {
+		t.Fatalf("机器之间传输文件失败: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(dst.Dir, "data"))
+	if err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("传输的文件应保留权限: %v, %v", info, err)
+	}
+	if got, err := os.ReadFile(filepath.Join(dst.Dir, "data")); err != nil || string(got) != string(data) {
+		t.Errorf("传输的文件内容不正确: %q, %v", got, err)
+	}
+	if last.Transferred != int64(len(data)) || last.Total != int64(len(data)) {
+		t.Errorf("传输完成时的进度不正确: %+v", last)
+	}
+
+	if err := srcConn.CopyTo(dstConn, src.Dir, dst.Dir); err == nil {
+		t.Error("源路径是目录时应返回错误")
+	}
+	if err := srcConn.CopyTo(dstConn, filepath.Join(src.Dir, "missing"), dst.Dir); err == nil {
+		t.Error("源文件不存在时应返回错误")
+	}
+}
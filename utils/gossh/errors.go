@@ -0,0 +1,65 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 11:05:47
+ */
+
+package gossh
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lsne/goutils/utils/gocmd"
+)
+
+// Result 命令执行结果, 与 gocmd.Shell 返回的结构相同
+type Result = gocmd.Result
+
+// ExitError 远程命令已执行, 但退出码不为 0 或被信号终止
+type ExitError = gocmd.ExitError
+
+// TimeoutError 命令执行超时或被取消时返回的错误, 包含已经捕获到的部分输出
+type TimeoutError = gocmd.TimeoutError
+
+// Output 命令输出的实时转发和缓存设置, 与 gocmd.Shell 使用相同的结构
+type Output = gocmd.Output
+
+// Line 命令输出的一行
+type Line = gocmd.Line
+
+// Encoding 远程命令输出的编码, 同 gocmd.Encoding
+type Encoding = gocmd.Encoding
+
+// RetryPolicy 命令执行失败后的重试策略, 同 gocmd.RetryPolicy
+type RetryPolicy = gocmd.RetryPolicy
+
+// Watcher 扫描远程命令的输出并应答, 与 gocmd.Shell 使用相同的结构
+type Watcher = gocmd.Watcher
+
+// SentinelError 应答后的输出匹配到 Watcher.Sentinel, 命令已被中止
+type SentinelError = gocmd.SentinelError
+
+// PasswordRequiredError sudo 需要密码, 但没有设置 sudo 密码
+type PasswordRequiredError = gocmd.PasswordRequiredError
+
+// TransportError ssh 连接或会话异常, 远程命令可能没有执行或者执行状态未知
+type TransportError struct {
+	Host    string
+	Err     error
+	Started bool // 已经向远程提交了命令, 命令可能已经执行, 为 false 时命令一定没有执行
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("与机器: %s 的 ssh 会话异常: %v", e.Host, e.Err)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// RetryBeforeStart 向远程提交命令前 ssh 会话异常(如打开会话时 EOF、重连失败)时重试, 这时命令一定没有执行, 可以安全重试
+// 命令提交后的会话异常需要重试时(只适用于可以重复执行的命令), 使用 gocmd.RetryOnError[*TransportError]()
+func RetryBeforeStart(result *Result, err error) bool {
+	var terr *TransportError
+	return errors.As(err, &terr) && !terr.Started && !errors.Is(terr.Err, ErrClosed)
+}
@@ -0,0 +1,45 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 21:23:16
+ */
+
+package gossh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/lsne/goutils/utils/gocmd"
+)
+
+func TestRetryBeforeStart(t *testing.T) {
+	cases := []struct {
+		name  string
+		err   error
+		retry bool
+	}{
+		{"打开会话失败", &TransportError{Host: "h", Err: io.EOF}, true},
+		{"包装后的会话异常", fmt.Errorf("wrap: %w", &TransportError{Host: "h", Err: io.EOF}), true},
+		{"提交命令后连接中断", &TransportError{Host: "h", Err: io.EOF, Started: true}, false},
+		{"连接已关闭", &TransportError{Host: "h", Err: ErrClosed}, false},
+		{"命令执行失败", &ExitError{Result: &Result{ExitCode: 1}}, false},
+		{"其他错误", errors.New("x"), false},
+	}
+	for _, c := range cases {
+		if got := RetryBeforeStart(nil, c.err); got != c.retry {
+			t.Errorf("%s: RetryBeforeStart 应为 %v, 实际: %v", c.name, c.retry, got)
+		}
+	}
+
+	// 默认策略不重试命令提交后的会话异常
+	var o RunOptions
+	WithRetry(RetryPolicy{})(&o)
+	if o.retry.RetryIf(&Result{}, &TransportError{Host: "h", Err: io.EOF, Started: true}) {
+		t.Error("默认不应重试命令提交后的会话异常")
+	}
+	if !o.retry.RetryIf(&Result{Stderr: []byte(gocmd.TransientPatterns[0])}, &ExitError{}) {
+		t.Error("默认应重试输出中包含 TransientPatterns 的错误")
+	}
+}
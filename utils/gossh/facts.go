@@ -0,0 +1,284 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 23:41:06
+ */
+
+package gossh
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lsne/goutils/utils/strutil"
+)
+
+// 采集 Facts 时各部分输出之间的分隔行
+const factsSeparator = "--gossh-facts--"
+
+// 一次会话采集所有信息, 各部分依次为: os-release, 内核版本, 架构, 主机名, CPU 数, meminfo, 监听端口
+var factsScript = strings.Join([]string{
+	"cat /etc/os-release 2>/dev/null",
+	"cat /proc/sys/kernel/osrelease",
+	"uname -m",
+	"cat /proc/sys/kernel/hostname",
+	"grep -c ^processor /proc/cpuinfo",
+	"cat /proc/meminfo",
+	"ss -tln 2>/dev/null || netstat -tln 2>/dev/null",
+}, "; echo "+factsSeparator+"; ")
+
+// Facts 远程机器的基本信息
+type Facts struct {
+	OSID         string // /etc/os-release 中的 ID, 如 centos, ubuntu, rocky
+	OSVersion    string // /etc/os-release 中的 VERSION_ID, 如 7, 22.04
+	OSName       string // /etc/os-release 中的 PRETTY_NAME
+	Kernel       string // 内核版本, 如 5.14.0-362.el9.x86_64
+	Arch         string // 如 x86_64, aarch64
+	Hostname     string
+	CPUs         int
+	MemTotal     uint64   // 总内存, 单位字节
+	MemAvailable uint64   // 可用内存, 单位字节
+	ListenPorts  []uint16 // 正在监听的 TCP 端口, 已排序去重
+
+	// 以下字段只由 FactsFor 查询, Facts 返回的结果中为零值
+	DataPath   string // 数据目录
+	DataFree   uint64 // 数据目录所在文件系统的可用空间, 单位字节, 目录不存在时使用最近的已存在的上级目录
+	User       string // 用户名
+	UserExists bool   // 用户是否存在
+}
+
+// PortListening 端口是否已被监听
+func (f *Facts) PortListening(port uint16) bool {
+	_, found := slices.BinarySearch(f.ListenPorts, port)
+	return found
+}
+
+// Facts 采集远程机器的基本信息, 结果缓存在连接上, 需要重新采集时调用 RefreshFacts
+func (conn *Connection) Facts() (*Facts, error) {
+	conn.factsMu.Lock()
+	defer conn.factsMu.Unlock()
+
+	if conn.facts != nil {
+		return conn.facts, nil
+	}
+	return conn.gatherFacts()
+}
+
+// FactsFor 在 Facts 的基础上实时查询数据目录 dataPath 的可用空间和用户 user 是否存在, 用于安装前的检查
+// 基本信息使用 Facts 的缓存, dataPath 和 user 的结果不缓存, 为空时不查询对应的项
+func (conn *Connection) FactsFor(dataPath string, user string) (*Facts, error) {
+	cached, err := conn.Facts()
+	if err != nil {
+		return nil, err
+	}
+
+	facts := *cached
+	facts.ListenPorts = slices.Clone(cached.ListenPorts)
+	if dataPath != "" {
+		facts.DataPath = dataPath
+		if facts.DataFree, err = conn.FreeDiskByte(dataPath); err != nil {
+			return nil, err
+		}
+	}
+	if user != "" {
+		facts.User = user
+		if facts.UserExists, err = conn.UserExists(user); err != nil {
+			return nil, err
+		}
+	}
+	return &facts, nil
+}
+
+// RefreshFacts 重新采集远程机器的基本信息并更新缓存
+func (conn *Connection) RefreshFacts() (*Facts, error) {
+	conn.factsMu.Lock()
+	defer conn.factsMu.Unlock()
+	return conn.gatherFacts()
+}
+
+func (conn *Connection) gatherFacts() (*Facts, error) {
+	result, err := conn.Exec(factsScript, WithHide(true), WithLocale("C"), WithReadOnly(true))
+	if err != nil {
+		var exitErr *ExitError
+		// 最后一条命令(ss/netstat)不存在时退出码不为 0, 不影响其他信息
+		if !errors.As(err, &exitErr) {
+			return nil, fmt.Errorf(GOSSH_ERR_FORMAT, conn.Host, "采集机器信息", err, result.Stdout, result.Stderr)
+		}
+	}
+
+	facts, err := parseFacts(string(result.Stdout))
+	if err != nil {
+		return nil, fmt.Errorf("在机器: %s 上, 解析机器信息失败: %v", conn.Host, err)
+	}
+	conn.facts = facts
+	return facts, nil
+}
+
+func parseFacts(output string) (*Facts, error) {
+	parts := strings.Split(output, factsSeparator+"\n")
+	if len(parts) != 7 {
+		return nil, fmt.Errorf("输出格式不正确: %q", output)
+	}
+
+	facts := &Facts{
+		Kernel:   strings.TrimSpace(parts[1]),
+		Arch:     strings.TrimSpace(parts[2]),
+		Hostname: strings.TrimSpace(parts[3]),
+	}
+
+	osRelease := parseKeyValues(parts[0], "=")
+	facts.OSID = osRelease["ID"]
+	facts.OSVersion = osRelease["VERSION_ID"]
+	facts.OSName = osRelease["PRETTY_NAME"]
+
+	var err error
+	if facts.CPUs, err = strconv.Atoi(strings.TrimSpace(parts[4])); err != nil {
+		return nil, fmt.Errorf("CPU 数(%s)不合法", strings.TrimSpace(parts[4]))
+	}
+
+	meminfo := parseKeyValues(parts[5], ":")
+	if facts.MemTotal, err = parseMeminfoBytes(meminfo["MemTotal"]); err != nil {
+		return nil, err
+	}
+	if available, ok := meminfo["MemAvailable"]; ok {
+		if facts.MemAvailable, err = parseMeminfoBytes(available); err != nil {
+			return nil, err
+		}
+	}
+
+	facts.ListenPorts = parseListenPorts(parts[6])
+	return facts, nil
+}
+
+// parseKeyValues 解析 key=value 或 key: value 格式的多行文本, 去掉 value 两端的空白和引号
+func parseKeyValues(s string, sep string) map[string]string {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), sep)
+		if !ok {
+			continue
+		}
+		values[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return values
+}
+
+// parseMeminfoBytes 解析 /proc/meminfo 中 "16318852 kB" 格式的值
+func parseMeminfoBytes(s string) (uint64, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("内存信息(%s)不合法", s)
+	}
+	n, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("内存信息(%s)不合法", s)
+	}
+	if len(fields) > 1 && strings.EqualFold(fields[1], "kB") {
+		n *= 1024
+	}
+	return n, nil
+}
+
+// parseListenPorts 解析 ss -tln 或 netstat -tln 的输出, 返回排序去重后的监听端口
+func parseListenPorts(s string) []uint16 {
+	var ports []uint16
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		var local string
+		switch {
+		case len(fields) >= 5 && fields[0] == "LISTEN": // ss: State Recv-Q Send-Q Local:Port Peer:Port
+			local = fields[3]
+		case len(fields) >= 6 && strings.HasPrefix(fields[0], "tcp") && fields[5] == "LISTEN": // netstat: Proto Recv-Q Send-Q Local Foreign State
+			local = fields[3]
+		default:
+			continue
+		}
+
+		i := strings.LastIndexAny(local, ":.")
+		if i < 0 {
+			continue
+		}
+		if port, err := strconv.ParseUint(local[i+1:], 10, 16); err == nil {
+			ports = append(ports, uint16(port))
+		}
+	}
+	slices.Sort(ports)
+	return slices.Compact(ports)
+}
+
+// ListeningPorts 实时查询远程机器正在监听的 TCP 端口, 不使用缓存
+func (conn *Connection) ListeningPorts() ([]uint16, error) {
+	cmd := "ss -tln 2>/dev/null || netstat -tln"
+	result, err := conn.Exec(cmd, WithHide(true), WithLocale("C"), WithReadOnly(true))
+	if err != nil {
+		return nil, fmt.Errorf(GOSSH_ERR_FORMAT, conn.Host, cmd, err, result.Stdout, result.Stderr)
+	}
+	return parseListenPorts(string(result.Stdout)), nil
+}
+
+// PortAvailable 远程机器上的端口是否未被监听, 对应本地的 netutil.LocalPortAvailable
+func (conn *Connection) PortAvailable(port uint16) (bool, error) {
+	ports, err := conn.ListeningPorts()
+	if err != nil {
+		return false, err
+	}
+	return !slices.Contains(ports, port), nil
+}
+
+// FreeDiskByte 返回远程路径所在文件系统的可用空间(字节), 路径不存在时使用最近的已存在的上级目录
+// 对应本地的 diskutil.GetFreeDiskByte
+func (conn *Connection) FreeDiskByte(path string) (uint64, error) {
+	cmd := fmt.Sprintf(`p=%s; while [ ! -e "$p" ]; do p=$(dirname "$p"); done; df -Pk "$p"`, strutil.Quote(path))
+	result, err := conn.Exec(cmd, WithHide(true), WithLocale("C"), WithReadOnly(true))
+	if err != nil {
+		return 0, fmt.Errorf(GOSSH_ERR_FORMAT, conn.Host, cmd, err, result.Stdout, result.Stderr)
+	}
+	free, err := parseDF(string(result.Stdout))
+	if err != nil {
+		return 0, fmt.Errorf("在机器: %s 上, 解析(%s)的输出失败: %v", conn.Host, cmd, err)
+	}
+	return free, nil
+}
+
+// FreeDiskGB 与 FreeDiskByte 相同, 单位为 GB
+func (conn *Connection) FreeDiskGB(path string) (uint64, error) {
+	free, err := conn.FreeDiskByte(path)
+	return free >> 30, err
+}
+
+// parseDF 解析 df -Pk 的输出, 返回可用空间(字节)
+func parseDF(s string) (uint64, error) {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) < 2 {
+		return 0, fmt.Errorf("输出格式不正确: %q", s)
+	}
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 6 {
+		return 0, fmt.Errorf("输出格式不正确: %q", s)
+	}
+	available, err := strconv.ParseUint(fields[3], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("可用空间(%s)不合法", fields[3])
+	}
+	return available * 1024, nil
+}
+
+// UserExists 远程机器上用户是否存在, 通过 id 命令查询, 包括 LDAP 等非本地用户
+func (conn *Connection) UserExists(name string) (bool, error) {
+	cmd := fmt.Sprintf("id -u %s", strutil.Quote(name))
+	result, err := conn.Exec(cmd, WithHide(true), WithLocale("C"), WithReadOnly(true))
+	if err == nil {
+		return true, nil
+	}
+
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf(GOSSH_ERR_FORMAT, conn.Host, cmd, err, result.Stdout, result.Stderr)
+}
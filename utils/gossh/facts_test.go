@@ -0,0 +1,52 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 23:58:21
+ */
+
+package gossh
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseFacts(t *testing.T) {
+	output := strings.Join([]string{
+		"NAME=\"Rocky Linux\"\nID=\"rocky\"\nVERSION_ID=\"9.3\"\nPRETTY_NAME=\"Rocky Linux 9.3 (Blue Onyx)\"\n",
+		"5.14.0-362.8.1.el9_3.x86_64\n",
+		"x86_64\n",
+		"db01\n",
+		"8\n",
+		"MemTotal:       16318852 kB\nMemFree:         1024000 kB\nMemAvailable:    8159426 kB\n",
+		"State  Recv-Q Send-Q Local Address:Port Peer Address:Port Process\n" +
+			"LISTEN 0      128          0.0.0.0:22        0.0.0.0:*\n" +
+			"LISTEN 0      511        127.0.0.1:6379      0.0.0.0:*\n" +
+			"LISTEN 0      128             [::]:22           [::]:*\n",
+	}, factsSeparator+"\n")
+
+	facts, err := parseFacts(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := Facts{
+		OSID: "rocky", OSVersion: "9.3", OSName: "Rocky Linux 9.3 (Blue Onyx)",
+		Kernel: "5.14.0-362.8.1.el9_3.x86_64", Arch: "x86_64", Hostname: "db01", CPUs: 8,
+		MemTotal: 16318852 * 1024, MemAvailable: 8159426 * 1024,
+	}
+	got := *facts
+	got.ListenPorts = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("解析结果: %+v, 期望: %+v", got, want)
+	}
+	if !slices.Equal(facts.ListenPorts, []uint16{22, 6379}) || !facts.PortListening(6379) || facts.PortListening(3306) {
+		t.Errorf("监听端口不正确: %v", facts.ListenPorts)
+	}
+
+	free, err := parseDF("Filesystem 1024-blocks Used Available Capacity Mounted on\n/dev/vda1 41152736 9130212 30118812 24% /\n")
+	if err != nil || free != 30118812*1024 {
+		t.Errorf("解析 df 输出: %d, %v", free, err)
+	}
+}
@@ -0,0 +1,318 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 23:12:40
+ */
+
+package gossh
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/lsne/goutils/utils/fileutil"
+	"github.com/lsne/goutils/utils/gocmd"
+	"github.com/lsne/goutils/utils/strutil"
+
+	"github.com/pkg/sftp"
+)
+
+// ReadFile 读取远程文件的全部内容
+func (conn *Connection) ReadFile(name string) ([]byte, error) {
+	if err := conn.ensureConnected(); err != nil {
+		return nil, err
+	}
+
+	f, err := conn.Open(filepath.ToSlash(name))
+	if err != nil {
+		return nil, fmt.Errorf("在机器: %s 上, 打开文件(%s)失败: %v", conn.Host, name, err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		return nil, fmt.Errorf("在机器: %s 上, 读取文件(%s)失败: %v", conn.Host, name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteFile 原子地写入远程文件: 先写入同目录下的临时文件, 设置权限后再 rename 覆盖目标文件
+// 写入过程中出错不会破坏已有的文件. 目标文件会被替换为新文件, 属主为当前登录用户, 需要时再调用 ChownByName
+func (conn *Connection) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return conn.WriteFileFrom(name, bytes.NewReader(data), perm)
+}
+
+// WriteFileFrom 与 WriteFile 相同, 内容从 r 读取
+// 设置了 Audit 时以 "write <name>" 记录
+func (conn *Connection) WriteFileFrom(name string, r io.Reader, perm os.FileMode) error {
+	name = filepath.ToSlash(name)
+	return conn.change("write "+strutil.Quote(name), func(client *sftp.Client) error {
+		return conn.writeFileFrom(client, name, r, perm)
+	})
+}
+
+func (conn *Connection) writeFileFrom(client *sftp.Client, name string, r io.Reader, perm os.FileMode) error {
+	tmp := path.Join(path.Dir(name), "."+path.Base(name)+".tmp-"+strutil.GenerateString(8))
+	f, err := client.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_EXCL)
+	if err != nil {
+		return fmt.Errorf("在机器: %s 上, 创建临时文件(%s)失败: %v", conn.Host, tmp, err)
+	}
+
+	if _, err = f.ReadFrom(r); err == nil {
+		err = f.Chmod(perm)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = client.PosixRename(tmp, name)
+	}
+	if err != nil {
+		_ = client.Remove(tmp)
+		return fmt.Errorf("在机器: %s 上, 写入文件(%s)失败: %v", conn.Host, name, err)
+	}
+	return nil
+}
+
+// AppendFile 在远程文件末尾追加内容, 文件不存在时以 perm 权限创建
+// 设置了 Audit 时以 "append <name>" 记录
+func (conn *Connection) AppendFile(name string, data []byte, perm os.FileMode) error {
+	name = filepath.ToSlash(name)
+	return conn.change("append "+strutil.Quote(name), func(client *sftp.Client) error {
+		return conn.appendFile(client, name, data, perm)
+	})
+}
+
+func (conn *Connection) appendFile(client *sftp.Client, name string, data []byte, perm os.FileMode) error {
+	_, statErr := client.Stat(name)
+	f, err := client.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND)
+	if err != nil {
+		return fmt.Errorf("在机器: %s 上, 打开文件(%s)失败: %v", conn.Host, name, err)
+	}
+	defer f.Close()
+
+	if os.IsNotExist(statErr) {
+		if err := f.Chmod(perm); err != nil {
+			return err
+		}
+	}
+
+	// sftp 按偏移量写入, 有的服务端(如 pkg/sftp)忽略 O_APPEND, 需要先移动到文件末尾
+	if _, err := f.Seek(0, io.SeekEnd); err != nil {
+		return fmt.Errorf("在机器: %s 上, 获取文件(%s)的大小失败: %v", conn.Host, name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("在机器: %s 上, 写入文件(%s)失败: %v", conn.Host, name, err)
+	}
+	return nil
+}
+
+// LookupUser 根据远程机器的 /etc/passwd 查询用户的 uid 和主组 gid
+func (conn *Connection) LookupUser(name string) (uid int, gid int, err error) {
+	fields, err := conn.lookupEntry("/etc/passwd", name, 4)
+	if err != nil {
+		return 0, 0, err
+	}
+	if uid, err = strconv.Atoi(fields[2]); err != nil {
+		return 0, 0, fmt.Errorf("在机器: %s 上, 用户(%s)的 uid(%s)不合法", conn.Host, name, fields[2])
+	}
+	if gid, err = strconv.Atoi(fields[3]); err != nil {
+		return 0, 0, fmt.Errorf("在机器: %s 上, 用户(%s)的 gid(%s)不合法", conn.Host, name, fields[3])
+	}
+	return uid, gid, nil
+}
+
+// LookupGroup 根据远程机器的 /etc/group 查询组的 gid
+func (conn *Connection) LookupGroup(name string) (int, error) {
+	fields, err := conn.lookupEntry("/etc/group", name, 3)
+	if err != nil {
+		return 0, err
+	}
+	gid, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, fmt.Errorf("在机器: %s 上, 组(%s)的 gid(%s)不合法", conn.Host, name, fields[2])
+	}
+	return gid, nil
+}
+
+// lookupEntry 在 /etc/passwd 格式的文件中查找第一列为 name 的行, 返回以 ':' 分割的各列
+func (conn *Connection) lookupEntry(file string, name string, minFields int) ([]string, error) {
+	data, err := conn.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ":")
+		if len(fields) >= minFields && fields[0] == name {
+			return fields, nil
+		}
+	}
+	return nil, fmt.Errorf("在机器: %s 上, %s 中不存在 %s", conn.Host, file, name)
+}
+
+// ChownByName 按用户名和组名修改远程文件的属主, group 为空时使用用户的主组
+func (conn *Connection) ChownByName(name string, user string, group string) error {
+	uid, gid, err := conn.lookupOwner(user, group)
+	if err != nil {
+		return err
+	}
+
+	name = filepath.ToSlash(name)
+	return conn.change(fmt.Sprintf("chown %s %s", owner(user, group), strutil.Quote(name)), func(client *sftp.Client) error {
+		return client.Chown(name, uid, gid)
+	})
+}
+
+// ChownAllByName 与 ChownByName 相同, 递归修改目录下的所有文件, 不跟随软链接
+func (conn *Connection) ChownAllByName(name string, user string, group string) error {
+	uid, gid, err := conn.lookupOwner(user, group)
+	if err != nil {
+		return err
+	}
+
+	name = filepath.ToSlash(name)
+	return conn.change(fmt.Sprintf("chown -R %s %s", owner(user, group), strutil.Quote(name)), func(client *sftp.Client) error {
+		return conn.chownAll(client, name, uid, gid)
+	})
+}
+
+func (conn *Connection) chownAll(client *sftp.Client, name string, uid, gid int) error {
+	walker := client.Walk(name)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+		if walker.Stat().Mode()&os.ModeSymlink != 0 {
+			continue
+		}
+		if err := client.Chown(walker.Path(), uid, gid); err != nil {
+			return fmt.Errorf("在机器: %s 上, 修改(%s)的属主失败: %v", conn.Host, walker.Path(), err)
+		}
+	}
+	return nil
+}
+
+// owner 返回记录到审计日志中的 user[:group]
+func owner(user string, group string) string {
+	if group == "" {
+		return user
+	}
+	return user + ":" + group
+}
+
+func (conn *Connection) lookupOwner(user string, group string) (int, int, error) {
+	uid, gid, err := conn.LookupUser(user)
+	if err != nil {
+		return 0, 0, err
+	}
+	if group != "" {
+		if gid, err = conn.LookupGroup(group); err != nil {
+			return 0, 0, err
+		}
+	}
+	return uid, gid, nil
+}
+
+// RemoveAll 递归删除远程文件或目录, 不跟随软链接. 路径不存在时返回 nil
+// 为了防止误删, 拒绝删除 gocmd.SystemDirs 中的系统目录
+func (conn *Connection) RemoveAll(name string) error {
+	name = filepath.ToSlash(name)
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("要删除的路径不能为空")
+	}
+
+	// 解析相对路径和 "..", 再检查是否是系统目录
+	real, err := conn.RealPath(name)
+	if err != nil {
+		real = path.Clean(name)
+	}
+	if slices.Contains(gocmd.SystemDirs, real) || slices.Contains(gocmd.SystemDirs, path.Clean(name)) {
+		return fmt.Errorf("目录(%s)是系统目录， 不允许删除", name)
+	}
+
+	info, err := conn.Lstat(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return conn.change("rm -rf "+strutil.Quote(name), func(client *sftp.Client) error {
+		return removeAll(client, name, info)
+	})
+}
+
+func removeAll(client *sftp.Client, name string, info os.FileInfo) error {
+	if !info.IsDir() {
+		return client.Remove(name)
+	}
+
+	entries, err := client.ReadDir(name)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := removeAll(client, path.Join(name, entry.Name()), entry); err != nil {
+			return err
+		}
+	}
+	return client.RemoveDirectory(name)
+}
+
+// SymlinkForce 创建软链接 newname 指向 oldname, newname 已经是文件或软链接时先删除(同 ln -sfn)
+func (conn *Connection) SymlinkForce(oldname, newname string) error {
+	oldname, newname = filepath.ToSlash(oldname), filepath.ToSlash(newname)
+
+	info, err := conn.Lstat(newname)
+	if err == nil && info.IsDir() {
+		return fmt.Errorf("在机器: %s 上, %s 是一个目录", conn.Host, newname)
+	}
+	return conn.change("ln -sfn "+strutil.Quote(oldname)+" "+strutil.Quote(newname), func(client *sftp.Client) error {
+		if err == nil {
+			if err := client.Remove(newname); err != nil {
+				return err
+			}
+		}
+		return client.Symlink(oldname, newname)
+	})
+}
+
+// BackupFile 在远程机器上将文件复制一份, 文件名加上 fileutil.BackupSuffix() 后缀, 保留权限
+func (conn *Connection) BackupFile(src string) error {
+	src = filepath.ToSlash(src)
+	dst := path.Clean(src) + fileutil.BackupSuffix()
+	return conn.change("cp -p "+strutil.Quote(src)+" "+strutil.Quote(dst), func(client *sftp.Client) error {
+		sf, err := client.Open(src)
+		if err != nil {
+			return fmt.Errorf("在机器: %s 上, 打开文件(%s)失败: %v", conn.Host, src, err)
+		}
+		defer sf.Close()
+
+		info, err := sf.Stat()
+		if err != nil {
+			return err
+		}
+		return conn.writeFileFrom(client, dst, sf, info.Mode().Perm())
+	})
+}
+
+// MoveToBackup 在远程机器上将文件或目录重命名, 加上 fileutil.BackupSuffix() 后缀
+func (conn *Connection) MoveToBackup(src string) error {
+	src = filepath.ToSlash(src)
+	dst := path.Clean(src) + fileutil.BackupSuffix()
+	return conn.change("mv "+strutil.Quote(src)+" "+strutil.Quote(dst), func(client *sftp.Client) error {
+		if err := client.PosixRename(src, dst); err != nil {
+			return fmt.Errorf("在机器: %s 上, 重命名(%s)为(%s)失败: %v", conn.Host, src, dst, err)
+		}
+		return nil
+	})
+}
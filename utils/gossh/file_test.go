@@ -0,0 +1,142 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-20 16:02:27
+ */
+
+package gossh
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/lsne/goutils/utils/gossh/sshtest"
+)
+
+func TestServerFiles(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	file := filepath.Join(s.Dir, "conf", "my.cnf")
+	if err := conn.MkdirAll(filepath.Dir(file)); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.WriteFile(file, []byte("a=1\n"), 0640); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if err := conn.AppendFile(file, []byte("b=2\n"), 0600); err != nil {
+		t.Fatalf("追加文件失败: %v", err)
+	}
+	if data, err := conn.ReadFile(file); err != nil || string(data) != "a=1\nb=2\n" {
+		t.Errorf("读取的文件内容不正确: %q, %v", data, err)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("追加已存在的文件不应修改权限: %v, %v", info, err)
+	}
+	if err := conn.WriteFileFrom(file, strings.NewReader("c=3\n"), 0644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "c=3\n" {
+		t.Errorf("覆盖写入的文件内容不正确: %q, %v", data, err)
+	}
+
+	// 按当前用户的用户名和主组名修改属主, 不需要特权
+	u, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := user.LookupGroupId(u.Gid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uid, gid, err := conn.LookupUser(u.Username); err != nil || strconv.Itoa(uid) != u.Uid || strconv.Itoa(gid) != u.Gid {
+		t.Errorf("查询用户 %s 不正确: %d, %d, %v", u.Username, uid, gid, err)
+	}
+	if gid, err := conn.LookupGroup(g.Name); err != nil || strconv.Itoa(gid) != g.Gid {
+		t.Errorf("查询组 %s 不正确: %d, %v", g.Name, gid, err)
+	}
+	if _, _, err := conn.LookupUser("sshtest-no-such-user"); err == nil {
+		t.Error("用户不存在时应返回错误")
+	}
+	if err := conn.ChownByName(file, u.Username, g.Name); err != nil {
+		t.Errorf("修改属主失败: %v", err)
+	}
+	if err := conn.ChownAllByName(filepath.Dir(file), u.Username, ""); err != nil {
+		t.Errorf("递归修改属主失败: %v", err)
+	}
+	if info, err := os.Stat(file); err == nil {
+		if st := info.Sys().(*syscall.Stat_t); strconv.Itoa(int(st.Uid)) != u.Uid || strconv.Itoa(int(st.Gid)) != u.Gid {
+			t.Errorf("属主不正确: %d:%d", st.Uid, st.Gid)
+		}
+	}
+
+	// 软链接已存在时替换, 目标是目录时返回错误
+	link := filepath.Join(s.Dir, "current")
+	for _, target := range []string{filepath.Dir(file), file} {
+		if err := conn.SymlinkForce(target, link); err != nil {
+			t.Fatalf("创建软链接失败: %v", err)
+		}
+		if got, err := os.Readlink(link); err != nil || got != target {
+			t.Errorf("软链接应指向 %s, 实际: %s, %v", target, got, err)
+		}
+	}
+	if err := conn.SymlinkForce(file, filepath.Dir(file)); err == nil {
+		t.Error("软链接的路径是目录时应返回错误")
+	}
+
+	// 备份时保留内容和权限, 移动备份后原文件不存在
+	if err := os.Chmod(file, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.BackupFile(file); err != nil {
+		t.Fatalf("备份文件失败: %v", err)
+	}
+	backups, _ := filepath.Glob(file + ".bak.*")
+	if len(backups) != 1 {
+		t.Fatalf("应生成 1 个备份文件, 实际: %v", backups)
+	}
+	if info, err := os.Stat(backups[0]); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("备份文件应保留权限: %v, %v", info, err)
+	}
+	if err := os.Remove(backups[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.MoveToBackup(file); err != nil {
+		t.Fatalf("移动备份失败: %v", err)
+	}
+	if backups, _ = filepath.Glob(file + ".bak.*"); len(backups) != 1 || conn.IsExists(file) {
+		t.Errorf("移动备份后原文件不应存在, 备份文件: %v", backups)
+	}
+
+	// 递归删除不跟随软链接, 路径不存在时返回 nil, 拒绝删除系统目录
+	outside := filepath.Join(t.TempDir(), "keep")
+	if err := os.WriteFile(outside, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Dir(outside), filepath.Join(s.Dir, "conf", "outside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.RemoveAll(filepath.Join(s.Dir, "conf")); err != nil {
+		t.Fatalf("递归删除失败: %v", err)
+	}
+	if conn.IsExists(filepath.Join(s.Dir, "conf")) {
+		t.Error("递归删除后目录不应存在")
+	}
+	if _, err := os.Stat(outside); err != nil {
+		t.Errorf("递归删除不应跟随软链接: %v", err)
+	}
+	if err := conn.RemoveAll(filepath.Join(s.Dir, "missing")); err != nil {
+		t.Errorf("路径不存在时应返回 nil, 实际: %v", err)
+	}
+	for _, dir := range []string{"/etc", "/usr/../etc", ""} {
+		if err := conn.RemoveAll(dir); err == nil {
+			t.Errorf("不允许删除 %q", dir)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 00:16:53
+ */
+
+package gossh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Tunnel ssh 端口转发, 在 Listen 上监听, 将每个连接转发到 Target
+type Tunnel struct {
+	Listen string // 实际监听的地址, 本地转发时为本地地址, 远程转发时为远程机器上的地址
+	Target string // 转发的目标地址, 本地转发时由远程机器连接, 远程转发时由本机连接
+
+	conn     *Connection
+	listener net.Listener
+	dial     func() (net.Conn, error)
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// LocalForward 本地端口转发(ssh -L): 在本机 localAddr 上监听, 经由 ssh 连接转发到远程机器可以访问的 remoteAddr
+// localAddr 为空时监听 127.0.0.1 上的随机端口. 返回的 Tunnel 的 Host() 和 Port() 可以直接用于连接数据库, 如:
+//
+//	t, _ := conn.LocalForward("", "127.0.0.1:6379")
+//	defer t.Close()
+//	client, err := redisdao.NewRedisClient(t.Host(), t.Port(), password, timeout)
+func (conn *Connection) LocalForward(localAddr, remoteAddr string) (*Tunnel, error) {
+	if localAddr == "" {
+		localAddr = "127.0.0.1:0"
+	}
+
+	if _, err := conn.client(); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", localAddr)
+	if err != nil {
+		return nil, fmt.Errorf("本地监听(%s)失败: %v", localAddr, err)
+	}
+
+	t := &Tunnel{Listen: listener.Addr().String(), Target: remoteAddr, conn: conn, listener: listener}
+	t.dial = func() (net.Conn, error) {
+		// 每次都通过 client() 获取, 断线重连后转发仍然可用
+		client, err := conn.client()
+		if err != nil {
+			return nil, err
+		}
+		return client.Dial("tcp", remoteAddr)
+	}
+	return conn.startTunnel(t), nil
+}
+
+// RemoteForward 远程端口转发(ssh -R): 在远程机器的 remoteAddr 上监听, 将连接转发到本机可以访问的 localAddr
+// 远程监听建立在当前的 ssh 连接上, 连接断开重连后需要重新调用
+func (conn *Connection) RemoteForward(remoteAddr, localAddr string) (*Tunnel, error) {
+	client, err := conn.client()
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := client.Listen("tcp", remoteAddr)
+	if err != nil {
+		return nil, fmt.Errorf("在机器: %s 上监听(%s)失败: %v", conn.Host, remoteAddr, err)
+	}
+
+	t := &Tunnel{Listen: listener.Addr().String(), Target: localAddr, conn: conn, listener: listener}
+	t.dial = func() (net.Conn, error) {
+		return net.DialTimeout("tcp", localAddr, time.Duration(conn.Timeout)*time.Second)
+	}
+	return conn.startTunnel(t), nil
+}
+
+func (conn *Connection) startTunnel(t *Tunnel) *Tunnel {
+	t.conns = make(map[net.Conn]struct{})
+
+	conn.tunnelMu.Lock()
+	if conn.tunnels == nil {
+		conn.tunnels = make(map[*Tunnel]struct{})
+	}
+	conn.tunnels[t] = struct{}{}
+	conn.tunnelMu.Unlock()
+
+	t.wg.Go(t.serve)
+	return t
+}
+
+// closeTunnels 关闭连接上的所有端口转发
+func (conn *Connection) closeTunnels() {
+	conn.tunnelMu.Lock()
+	tunnels := conn.tunnels
+	conn.tunnels = nil
+	conn.tunnelMu.Unlock()
+
+	for t := range tunnels {
+		_ = t.close()
+	}
+}
+
+// Host 监听地址的 host 部分
+func (t *Tunnel) Host() string {
+	host, _, _ := net.SplitHostPort(t.Listen)
+	return host
+}
+
+// Port 监听地址的端口
+func (t *Tunnel) Port() uint16 {
+	_, port, _ := net.SplitHostPort(t.Listen)
+	p, _ := strconv.ParseUint(port, 10, 16)
+	return uint16(p)
+}
+
+// Close 停止监听, 断开所有正在转发的连接, 并等待转发结束
+func (t *Tunnel) Close() error {
+	t.conn.tunnelMu.Lock()
+	delete(t.conn.tunnels, t)
+	t.conn.tunnelMu.Unlock()
+
+	return t.close()
+}
+
+func (t *Tunnel) close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	err := t.listener.Close()
+	for c := range t.conns {
+		_ = c.Close()
+	}
+	t.mu.Unlock()
+
+	t.wg.Wait()
+	if errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+func (t *Tunnel) serve() {
+	for {
+		c, err := t.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		if !t.track(c) {
+			_ = c.Close()
+			return
+		}
+		t.wg.Go(func() {
+			defer t.untrack(c)
+
+			target, err := t.dial()
+			if err != nil {
+				return
+			}
+			if !t.track(target) {
+				_ = target.Close()
+				return
+			}
+			defer t.untrack(target)
+
+			pipe(c, target)
+		})
+	}
+}
+
+// track 记录正在转发的连接, 隧道已关闭时返回 false
+func (t *Tunnel) track(c net.Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return false
+	}
+	t.conns[c] = struct{}{}
+	return true
+}
+
+func (t *Tunnel) untrack(c net.Conn) {
+	t.mu.Lock()
+	delete(t.conns, c)
+	t.mu.Unlock()
+	_ = c.Close()
+}
+
+// pipe 双向复制数据, 任意一端关闭后同时关闭另一端
+func pipe(a, b net.Conn) {
+	var once sync.Once
+	closeBoth := func() {
+		once.Do(func() {
+			_ = a.Close()
+			_ = b.Close()
+		})
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		_, _ = io.Copy(a, b)
+		closeBoth()
+	})
+	_, _ = io.Copy(b, a)
+	closeBoth()
+	wg.Wait()
+}
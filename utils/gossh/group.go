@@ -0,0 +1,176 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 13:20:16
+ */
+
+package gossh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultConcurrency Group 默认的最大并发数
+const DefaultConcurrency = 10
+
+// ErrSkipped fail-fast 模式下, 因其他机器执行失败而未执行的机器返回该错误
+var ErrSkipped = errors.New("其他机器执行失败, 已跳过")
+
+// HostResult 单台机器的执行结果
+type HostResult struct {
+	Host     string // 机器地址, 即 Connection.Host
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int // 远程命令的退出码, 未能获取到退出码(如连接断开)时为 -1
+	Duration time.Duration
+	Err      error
+}
+
+// Group 在多台机器上并发执行相同的操作
+type Group struct {
+	Concurrency int  // 最大并发数, 小于等于 0 时使用 DefaultConcurrency
+	FailFast    bool // 为 true 时, 任意一台机器失败后取消其他机器正在执行的命令, 并跳过未开始的机器
+	conns       []*Connection
+}
+
+// NewGroup 创建 Group, 同一台机器可以用不同的用户或端口出现多次, user@host:port 相同时返回错误
+func NewGroup(conns ...*Connection) (*Group, error) {
+	seen := make(map[string]bool, len(conns))
+	for _, conn := range conns {
+		if seen[conn.String()] {
+			return nil, fmt.Errorf("机器 %s 重复", conn)
+		}
+		seen[conn.String()] = true
+	}
+	return &Group{Concurrency: DefaultConcurrency, conns: conns}, nil
+}
+
+// Connections 返回 Group 中的所有连接
+func (g *Group) Connections() []*Connection {
+	return g.conns
+}
+
+// Close 关闭所有连接
+func (g *Group) Close() error {
+	var errs []error
+	for _, conn := range g.conns {
+		if err := conn.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("关闭机器 %s 的连接失败: %w", conn.Host, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run 在所有机器上执行命令, 返回以 Connection.String()(user@host:port) 为 key 的执行结果
+// 未设置 WithHide(true) 且未通过 WithOutputWriters 设置标准输出时, 标准输出的每一行都会加上 "[host] " 前缀打印到终端
+func (g *Group) Run(ctx context.Context, cmd string, opts ...Option) (map[string]*HostResult, error) {
+	return g.exec(ctx, opts, func(ctx context.Context, conn *Connection, opts []Option) (*Result, error) {
+		return conn.ExecContext(ctx, cmd, opts...)
+	})
+}
+
+// Sudo 在所有机器上以 sudo 执行命令, 输出方式同 Run
+func (g *Group) Sudo(ctx context.Context, cmd string, opts ...Option) (map[string]*HostResult, error) {
+	return g.exec(ctx, opts, func(ctx context.Context, conn *Connection, opts []Option) (*Result, error) {
+		return conn.SudoExecContext(ctx, cmd, opts...)
+	})
+}
+
+// Scp 上传本地文件/目录到所有机器, source 和 target 的规则同 Connection.Scp, ctx 取消时中断正在进行的传输
+func (g *Group) Scp(ctx context.Context, source, target string, opts ...TransferOption) (map[string]*HostResult, error) {
+	return g.Each(ctx, func(ctx context.Context, conn *Connection) *HostResult {
+		return &HostResult{Err: conn.Scp(source, target, append(opts[:len(opts):len(opts)], WithContext(ctx))...)}
+	})
+}
+
+// Each 对每台机器并发执行 fn, 并发数和失败策略由 Concurrency 和 FailFast 控制
+// fn 返回结果的 Host 和 Duration 由 Each 填写, fn 返回 nil 时视为执行成功, 返回以 Connection.String() 为 key 的结果, 返回的 error 汇总了所有失败机器的错误
+// ctx 取消后未开始的机器不再执行, 其结果的 Err 为 ctx 的错误
+func (g *Group) Each(ctx context.Context, fn func(ctx context.Context, conn *Connection) *HostResult) (map[string]*HostResult, error) {
+	// fail-fast 模式下以 ErrSkipped 取消, 未开始的机器据此区分是被跳过还是调用方取消了 ctx
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	concurrency := g.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	results := make(map[string]*HostResult, len(g.conns))
+
+	for _, conn := range g.conns {
+		wg.Go(func() {
+			var result *HostResult
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+			}
+
+			if ctx.Err() != nil {
+				result = &HostResult{ExitCode: -1, Err: context.Cause(ctx)}
+			} else {
+				start := time.Now()
+				if result = fn(ctx, conn); result == nil {
+					result = &HostResult{}
+				}
+				result.Duration = time.Since(start)
+				if result.Err != nil && g.FailFast {
+					cancel(ErrSkipped)
+				}
+			}
+			result.Host = conn.Host
+
+			mu.Lock()
+			results[conn.String()] = result
+			mu.Unlock()
+		})
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, conn := range g.conns {
+		if err := results[conn.String()].Err; err != nil && !errors.Is(err, ErrSkipped) {
+			errs = append(errs, fmt.Errorf("%s: %w", conn, err))
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+func (g *Group) exec(ctx context.Context, opts []Option, run func(ctx context.Context, conn *Connection, opts []Option) (*Result, error)) (map[string]*HostResult, error) {
+	options := &RunOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	// 多台机器的输出写到同一个终端, 通过 Output.TagHost 给每行加上机器前缀, 按行加锁写入, 避免不同机器的输出混在一行
+	if !options.hide && options.output.Stdout == nil {
+		stdout := &lockedWriter{w: os.Stdout}
+		opts = append(opts[:len(opts):len(opts)], WithHide(true), WithOutputWriters(stdout, options.output.Stderr), WithTagHost(true))
+	}
+
+	return g.Each(ctx, func(ctx context.Context, conn *Connection) *HostResult {
+		result, err := run(ctx, conn, opts)
+		return &HostResult{Stdout: result.Stdout, Stderr: result.Stderr, ExitCode: result.ExitCode, Err: err}
+	})
+}
+
+// lockedWriter 加锁写入, 多台机器的 Capture 按行写入同一个 Writer 时, 每行只调用一次 Write
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
@@ -0,0 +1,98 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 14:10:25
+ */
+
+package gossh
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+func TestGroupEach(t *testing.T) {
+	g, err := NewGroup(&Connection{Host: "10.0.0.1"}, &Connection{Host: "10.0.0.2"}, &Connection{Host: "10.0.0.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Concurrency = 1
+
+	var running, maxRunning atomic.Int32
+	results, err := g.Each(context.Background(), func(ctx context.Context, conn *Connection) *HostResult {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for m := maxRunning.Load(); n > m && !maxRunning.CompareAndSwap(m, n); m = maxRunning.Load() {
+		}
+		if conn.Host == "10.0.0.2" {
+			return &HostResult{ExitCode: 1, Err: errors.New("exit 1")}
+		}
+		return &HostResult{}
+	})
+	if err == nil || len(results) != 3 {
+		t.Fatalf("应返回 3 个结果和 1 个错误, 实际: %d, %v", len(results), err)
+	}
+	if maxRunning.Load() != 1 {
+		t.Errorf("最大并发应为 1, 实际: %d", maxRunning.Load())
+	}
+	if results["@10.0.0.2:0"].ExitCode != 1 || results["@10.0.0.1:0"].Err != nil || results["@10.0.0.3:0"].Err != nil {
+		t.Errorf("continue-on-error 模式下其他机器应正常执行: %+v", results)
+	}
+
+	g.FailFast = true
+	results, _ = g.Each(context.Background(), func(ctx context.Context, conn *Connection) *HostResult {
+		return &HostResult{Err: errors.New("failed")}
+	})
+	var skipped int
+	for _, r := range results {
+		if errors.Is(r.Err, ErrSkipped) {
+			skipped++
+		}
+	}
+	if skipped != 2 {
+		t.Errorf("fail-fast 模式下应跳过 2 台机器, 实际: %d", skipped)
+	}
+
+	// ctx 已取消时, 非 fail-fast 模式下未开始的机器也不再执行, 不会绕过并发限制
+	g.FailFast = false
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var called atomic.Int32
+	results, err = g.Each(ctx, func(ctx context.Context, conn *Connection) *HostResult {
+		called.Add(1)
+		return &HostResult{}
+	})
+	if called.Load() != 0 || !errors.Is(err, context.Canceled) {
+		t.Errorf("ctx 取消后不应执行, 实际执行: %d 台, 错误: %v", called.Load(), err)
+	}
+	for key, r := range results {
+		if !errors.Is(r.Err, context.Canceled) || r.ExitCode != -1 {
+			t.Errorf("%s: ctx 取消后结果应为 context.Canceled, 实际: %+v", key, r)
+		}
+	}
+
+	// fn 返回 nil 时视为执行成功
+	results, err = g.Each(context.Background(), func(ctx context.Context, conn *Connection) *HostResult {
+		return nil
+	})
+	if err != nil || len(results) != 3 || results["@10.0.0.1:0"] == nil || results["@10.0.0.1:0"].Host != "10.0.0.1" {
+		t.Errorf("fn 返回 nil 时应得到空结果, 实际: %+v, %v", results, err)
+	}
+
+	if _, err := NewGroup(&Connection{Host: "10.0.0.1"}, &Connection{Host: "10.0.0.1"}); err == nil {
+		t.Error("重复的机器应返回错误")
+	}
+
+	// 同一台机器的不同用户或端口是不同的连接, 结果不会互相覆盖
+	g, err = NewGroup(&Connection{Host: "10.0.0.1", User: "root", Port: 22}, &Connection{Host: "10.0.0.1", User: "mysql", Port: 22}, &Connection{Host: "10.0.0.1", User: "root", Port: 2222})
+	if err != nil {
+		t.Fatalf("同一台机器的不同用户或端口不应返回错误: %v", err)
+	}
+	results, _ = g.Each(context.Background(), func(ctx context.Context, conn *Connection) *HostResult {
+		return &HostResult{Stdout: []byte(conn.User)}
+	})
+	if len(results) != 3 || string(results["mysql@10.0.0.1:22"].Stdout) != "mysql" || results["root@10.0.0.1:2222"].Host != "10.0.0.1" {
+		t.Errorf("结果应以 user@host:port 为 key: %+v", results)
+	}
+}
@@ -0,0 +1,194 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 10:12:31
+ */
+
+package gossh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mitchellh/go-homedir"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyPolicy 远程主机公钥校验策略
+type HostKeyPolicy int
+
+const (
+	// HostKeyStrict 严格校验, 主机公钥必须已存在于 known_hosts 中. 默认策略
+	HostKeyStrict HostKeyPolicy = iota
+	// HostKeyAcceptNew 首次连接时信任并将公钥追加到 known_hosts, 之后严格校验(trust-on-first-use)
+	HostKeyAcceptNew
+	// HostKeyInsecure 不校验主机公钥, 仅用于测试或明确知道风险的场景
+	HostKeyInsecure
+)
+
+func (p HostKeyPolicy) String() string {
+	switch p {
+	case HostKeyStrict:
+		return "strict"
+	case HostKeyAcceptNew:
+		return "accept-new"
+	case HostKeyInsecure:
+		return "insecure"
+	default:
+		return fmt.Sprintf("HostKeyPolicy(%d)", int(p))
+	}
+}
+
+// HostKeyError 主机公钥校验失败时返回的错误
+type HostKeyError struct {
+	Host        string // 连接地址, host:port
+	Fingerprint string // 远程主机提供的公钥指纹(SHA256)
+	KnownHosts  string // 使用的 known_hosts 文件
+	Line        int    // known_hosts 中与之冲突的记录行号, 为 0 表示该主机在 known_hosts 中不存在
+	Err         error
+}
+
+func (e *HostKeyError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("主机 %s 的公钥(%s)不在 %s 中", e.Host, e.Fingerprint, e.KnownHosts)
+	}
+	return fmt.Sprintf("主机 %s 的公钥(%s)与 %s 第 %d 行的记录不一致, 可能存在中间人攻击", e.Host, e.Fingerprint, e.KnownHosts, e.Line)
+}
+
+func (e *HostKeyError) Unwrap() error {
+	return e.Err
+}
+
+// Mismatch 是否是公钥与已有记录不一致(而非主机未知)
+func (e *HostKeyError) Mismatch() bool {
+	return e.Line != 0
+}
+
+// 多个连接并发 trust-on-first-use 时, 串行追加 known_hosts
+var knownHostsMu sync.Mutex
+
+// DefaultKnownHostsFile 返回当前用户的 ~/.ssh/known_hosts
+func DefaultKnownHostsFile() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", fmt.Errorf("获取当前用户家目录失败: %v", err)
+	}
+	return filepath.Join(home, ".ssh", "known_hosts"), nil
+}
+
+// hostKeyCallback 根据连接的校验策略生成 HostKeyCallback
+func (conn *Connection) hostKeyCallback() (ssh.HostKeyCallback, error) {
+	if conn.HostKeyPolicy == HostKeyInsecure {
+		return ssh.InsecureIgnoreHostKey(), nil
+	}
+
+	if conn.KnownHostsFile == "" {
+		file, err := DefaultKnownHostsFile()
+		if err != nil {
+			return nil, err
+		}
+		conn.KnownHostsFile = file
+	}
+	file := conn.KnownHostsFile
+	policy := conn.HostKeyPolicy
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		knownHostsMu.Lock()
+		defer knownHostsMu.Unlock()
+
+		if policy == HostKeyAcceptNew {
+			if err := ensureKnownHostsFile(file); err != nil {
+				return err
+			}
+		}
+
+		callback, err := knownhosts.New(file)
+		if errors.Is(err, os.ErrNotExist) {
+			// known_hosts 不存在时所有主机都是未知主机
+			return &HostKeyError{Host: hostname, Fingerprint: ssh.FingerprintSHA256(key), KnownHosts: file, Err: err}
+		}
+		if err != nil {
+			return fmt.Errorf("加载 known_hosts 文件(%s)失败: %w", file, err)
+		}
+
+		err = callback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		if len(keyErr.Want) > 0 {
+			return &HostKeyError{Host: hostname, Fingerprint: ssh.FingerprintSHA256(key), KnownHosts: file, Line: keyErr.Want[0].Line, Err: err}
+		}
+
+		if policy != HostKeyAcceptNew {
+			return &HostKeyError{Host: hostname, Fingerprint: ssh.FingerprintSHA256(key), KnownHosts: file, Err: err}
+		}
+		return appendKnownHost(file, hostname, key)
+	}, nil
+}
+
+// knownHostAlgorithms 返回 known_hosts 中已记录的该主机的公钥算法
+// 用于握手时优先协商已知类型的公钥, 避免服务端提供了其他类型的公钥而被误判为不一致
+func knownHostAlgorithms(file string, address string) []string {
+	callback, err := knownhosts.New(file)
+	if err != nil {
+		return nil
+	}
+
+	// 用一个不可能存在的公钥去校验, 从返回的错误里拿到已记录的公钥列表
+	var keyErr *knownhosts.KeyError
+	if err := callback(address, &net.TCPAddr{IP: net.IPv4zero}, probeKey{}); !errors.As(err, &keyErr) {
+		return nil
+	}
+
+	var algos []string
+	for _, k := range keyErr.Want {
+		switch t := k.Key.Type(); t {
+		case ssh.KeyAlgoRSA:
+			algos = append(algos, ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algos = append(algos, t)
+		}
+	}
+	return algos
+}
+
+func ensureKnownHostsFile(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+		return fmt.Errorf("创建目录(%s)失败: %v", filepath.Dir(file), err)
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_RDONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("创建 known_hosts 文件(%s)失败: %v", file, err)
+	}
+	return f.Close()
+}
+
+func appendKnownHost(file string, hostname string, key ssh.PublicKey) error {
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("打开 known_hosts 文件(%s)失败: %v", file, err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{hostname}, key)); err != nil {
+		return fmt.Errorf("写入 known_hosts 文件(%s)失败: %v", file, err)
+	}
+	return nil
+}
+
+// probeKey 仅用于查询 known_hosts 中的已知公钥
+type probeKey struct{}
+
+func (probeKey) Type() string                                 { return "gossh-probe" }
+func (probeKey) Marshal() []byte                              { return []byte("gossh-probe") }
+func (probeKey) Verify(data []byte, sig *ssh.Signature) error { return errors.New("gossh: probe key") }
@@ -0,0 +1,83 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 10:40:02
+ */
+
+package gossh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestHostKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ssh", "known_hosts")
+	remote := &net.TCPAddr{IP: net.ParseIP("192.168.0.2"), Port: 22}
+	key := newTestHostKey(t)
+	other := newTestHostKey(t)
+
+	// 默认策略为 HostKeyStrict, 未知主机返回错误, 不写入 known_hosts
+	strict := &Connection{KnownHostsFile: file}
+	if strict.HostKeyPolicy != HostKeyStrict {
+		t.Fatalf("默认策略应为 strict, 实际: %s", strict.HostKeyPolicy)
+	}
+	callback, err := strict.hostKeyCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hkErr *HostKeyError
+	if err := callback("192.168.0.2:22", remote, key); !errors.As(err, &hkErr) {
+		t.Fatalf("默认策略下未知主机应返回 HostKeyError, 实际: %v", err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("默认策略不应写入 known_hosts: %v", err)
+	}
+
+	tofu := &Connection{HostKeyPolicy: HostKeyAcceptNew, KnownHostsFile: file}
+	callback, err = tofu.hostKeyCallback()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := callback("192.168.0.2:22", remote, key); err != nil {
+		t.Fatalf("首次连接应信任并记录公钥: %v", err)
+	}
+	if err := callback("192.168.0.2:22", remote, key); err != nil {
+		t.Fatalf("已记录的公钥应校验通过: %v", err)
+	}
+
+	if err := callback("192.168.0.2:22", remote, other); !errors.As(err, &hkErr) || !hkErr.Mismatch() {
+		t.Fatalf("公钥不一致时应返回 HostKeyError, 实际: %v", err)
+	}
+	if hkErr.Fingerprint != ssh.FingerprintSHA256(other) {
+		t.Errorf("指纹应为 %s, 实际: %s", ssh.FingerprintSHA256(other), hkErr.Fingerprint)
+	}
+
+	callback, _ = strict.hostKeyCallback()
+	if err := callback("192.168.0.3:22", remote, key); !errors.As(err, &hkErr) || hkErr.Mismatch() {
+		t.Fatalf("严格模式下未知主机应返回 HostKeyError, 实际: %v", err)
+	}
+
+	if algos := knownHostAlgorithms(file, "192.168.0.2:22"); len(algos) != 1 || algos[0] != ssh.KeyAlgoED25519 {
+		t.Errorf("已知公钥算法应为 [%s], 实际: %v", ssh.KeyAlgoED25519, algos)
+	}
+}
@@ -0,0 +1,216 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 19:10:44
+ */
+
+package gossh
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// DefaultKeepAlive 默认每 30 秒发送一次 keepalive 探测
+const DefaultKeepAlive = 30
+
+// ErrClosed 连接已经调用过 Close
+var ErrClosed = errors.New("ssh 连接已关闭")
+
+// sshConn 一条 ssh 连接及其 sftp 客户端. 通过连接缓存复用时, 多个 Connection 共享同一个 sshConn
+type sshConn struct {
+	mu           sync.Mutex // 保护以下字段, 断线重连时会替换 client, sftpClient 和 jumpClients
+	clientConfig *ssh.ClientConfig
+	client       *ssh.Client
+	sftpClient   *sftp.Client
+	jumpClients  []*ssh.Client
+	agentConn    net.Conn
+	dead         bool          // ssh 连接已断开, 下次使用前需要重连
+	closed       bool          // 所有引用都已经 Close
+	done         chan struct{} // 关闭时 close, 用于停止 keepalive
+
+	key  string // 连接缓存中的 key, 不在缓存中时为空
+	refs int    // 引用计数, 由 pool.mu 保护
+}
+
+// IsAlive ssh 连接是否正常
+func (conn *Connection) IsAlive() bool {
+	if conn.shared == nil || conn.closed.Load() {
+		return false
+	}
+	s := conn.shared
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.closed && !s.dead && s.client != nil
+}
+
+// Reconnect 关闭当前的 ssh 连接并重新建立连接, 复用同一连接的其他调用方也会使用新的连接
+func (conn *Connection) Reconnect() error {
+	if conn.shared == nil || conn.closed.Load() {
+		return &TransportError{Host: conn.Host, Err: ErrClosed}
+	}
+	conn.shared.mu.Lock()
+	defer conn.shared.mu.Unlock()
+	if err := conn.reconnect(); err != nil {
+		return err
+	}
+	conn.syncClient()
+	return nil
+}
+
+// ensureConnected 连接已断开时重新建立连接
+func (conn *Connection) ensureConnected() error {
+	_, err := conn.client()
+	return err
+}
+
+// client 返回可用的 ssh 客户端, 连接已断开时先重连
+func (conn *Connection) client() (*ssh.Client, error) {
+	if err := conn.lock(); err != nil {
+		return nil, err
+	}
+	defer conn.shared.mu.Unlock()
+	return conn.shared.client, nil
+}
+
+// sftp 返回可用的 sftp 客户端, 连接已断开时先重连
+// 重连会关闭旧的客户端, 所以每次使用都要重新获取, 不能保存返回值
+func (conn *Connection) sftp() (*sftp.Client, error) {
+	if err := conn.lock(); err != nil {
+		return nil, err
+	}
+	defer conn.shared.mu.Unlock()
+	return conn.shared.sftpClient, nil
+}
+
+// lock 锁定 conn.shared.mu, 连接已断开时先重连. 返回 nil 时调用方需要解锁
+func (conn *Connection) lock() error {
+	if conn.shared == nil || conn.closed.Load() {
+		return &TransportError{Host: conn.Host, Err: ErrClosed}
+	}
+
+	s := conn.shared
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return &TransportError{Host: conn.Host, Err: ErrClosed}
+	}
+	if s.dead {
+		if err := conn.reconnect(); err != nil {
+			s.mu.Unlock()
+			return err
+		}
+	}
+	conn.syncClient()
+	return nil
+}
+
+// syncClient 更新内嵌的 sftp 客户端, 只在重连后变化时写入. 调用方需要持有 conn.shared.mu
+func (conn *Connection) syncClient() {
+	if conn.Client != conn.shared.sftpClient {
+		conn.Client = conn.shared.sftpClient
+	}
+}
+
+// reconnect 调用方需要持有 conn.shared.mu
+func (conn *Connection) reconnect() error {
+	s := conn.shared
+	if s.closed {
+		return &TransportError{Host: conn.Host, Err: ErrClosed}
+	}
+
+	_ = s.closeClients()
+	if err := conn.connect(); err != nil {
+		s.dead = true
+		return &TransportError{Host: conn.Host, Err: fmt.Errorf("重新连接失败: %w", err)}
+	}
+	s.dead = false
+	return nil
+}
+
+// close 关闭 sftp 客户端、ssh 连接、跳板机连接和 ssh-agent 连接, 并停止 keepalive
+func (s *sshConn) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+
+	err := s.closeClients()
+	s.closeAgent()
+	return err
+}
+
+// closeClients 关闭 sftp 客户端、ssh 连接以及跳板机连接, 调用方需要持有 s.mu
+func (s *sshConn) closeClients() error {
+	var errs []error
+	if s.sftpClient != nil {
+		errs = append(errs, s.sftpClient.Close())
+	}
+	if s.client != nil {
+		errs = append(errs, s.client.Close())
+	}
+	s.closeJumpClients()
+	return errors.Join(errs...)
+}
+
+// watch ssh 连接断开(包括 keepalive 探测失败后主动关闭)时, 标记连接需要重连
+func (s *sshConn) watch(client *ssh.Client) {
+	_ = client.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == client {
+		s.dead = true
+	}
+}
+
+// keepalive 每 interval 发送 keepalive@openssh.com 请求, 超过 timeout 没有应答或失败时关闭 ssh 连接, 由 watch 标记为需要重连
+func (s *sshConn) keepalive(interval, timeout time.Duration) {
+	if timeout <= 0 || timeout > interval {
+		timeout = interval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		s.mu.Lock()
+		client, dead := s.client, s.dead
+		s.mu.Unlock()
+		if dead || client == nil {
+			continue
+		}
+
+		errc := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			errc <- err
+		}()
+
+		select {
+		case err := <-errc:
+			if err != nil {
+				client.Close()
+			}
+		case <-time.After(timeout):
+			client.Close()
+		case <-s.done:
+			return
+		}
+	}
+}
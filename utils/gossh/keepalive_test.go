@@ -0,0 +1,183 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-19 14:20:31
+ */
+
+package gossh
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lsne/goutils/utils/gossh/sshtest"
+)
+
+// waitDead 等待连接被标记为断开
+func waitDead(t *testing.T, conn *Connection, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for conn.IsAlive() {
+		if time.Now().After(deadline) {
+			t.Fatalf("连接在 %v 内没有被标记为断开", timeout)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestServerReconnect(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	if _, err := conn.Exec("true", WithHide(true)); err != nil {
+		t.Fatalf("执行命令失败: %v", err)
+	}
+
+	// 断线后下一次 Exec 自动重连
+	s.CloseConnections()
+	waitDead(t, conn, 5*time.Second)
+	if result, err := conn.Exec("echo ok", WithHide(true)); err != nil || string(result.Stdout) != "ok\n" {
+		t.Fatalf("断线后 Exec 应自动重连, 输出: %q, 错误: %v", result.Stdout, err)
+	}
+	if !conn.IsAlive() {
+		t.Error("重连后 IsAlive 应为 true")
+	}
+
+	// 断线后下一次 sftp 操作自动重连
+	s.CloseConnections()
+	waitDead(t, conn, 5*time.Second)
+	if _, err := conn.Stat(s.Dir); err != nil {
+		t.Fatalf("断线后 sftp 操作应自动重连: %v", err)
+	}
+	if _, err := conn.Exec("true", WithHide(true)); err != nil {
+		t.Fatalf("sftp 重连后执行命令失败: %v", err)
+	}
+
+	if err := conn.Close(); err != nil {
+		t.Fatalf("关闭连接失败: %v", err)
+	}
+	var terr *TransportError
+	if _, err := conn.Exec("true", WithHide(true)); !errors.As(err, &terr) || !errors.Is(terr.Err, ErrClosed) {
+		t.Errorf("Close 后应返回 ErrClosed, 实际: %v", err)
+	}
+	if err := conn.Reconnect(); !errors.As(err, &terr) || !errors.Is(terr.Err, ErrClosed) {
+		t.Errorf("Close 后 Reconnect 应返回 ErrClosed, 实际: %v", err)
+	}
+}
+
+// pausableProxy 转发到 ssh 测试服务的 TCP 代理, 暂停时不再转发数据, 模拟网络中断但 TCP 连接未断开
+type pausableProxy struct {
+	listener net.Listener
+	paused   atomic.Bool
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newPausableProxy(t *testing.T, target string) *pausableProxy {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("代理监听失败: %v", err)
+	}
+	p := &pausableProxy{listener: l}
+	go p.serve(target)
+	t.Cleanup(p.close)
+	return p
+}
+
+func (p *pausableProxy) serve(target string) {
+	for {
+		c, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		u, err := net.Dial("tcp", target)
+		if err != nil {
+			_ = c.Close()
+			continue
+		}
+		p.mu.Lock()
+		p.conns = append(p.conns, c, u)
+		p.mu.Unlock()
+		go p.copy(c, u)
+		go p.copy(u, c)
+	}
+}
+
+func (p *pausableProxy) copy(dst, src net.Conn) {
+	defer dst.Close()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		for p.paused.Load() {
+			time.Sleep(10 * time.Millisecond)
+		}
+		if n > 0 {
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				_ = src.Close()
+			}
+			return
+		}
+	}
+}
+
+func (p *pausableProxy) port() uint16 {
+	return uint16(p.listener.Addr().(*net.TCPAddr).Port)
+}
+
+func (p *pausableProxy) close() {
+	p.paused.Store(false)
+	_ = p.listener.Close()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.conns {
+		_ = c.Close()
+	}
+}
+
+func TestServerKeepAlive(t *testing.T) {
+	s := sshtest.NewServer(t)
+	proxy := newPausableProxy(t, s.Addr())
+
+	// 经过代理连接时端口与 known_hosts 中记录的不同, 不校验主机公钥
+	conn, err := NewConnection(s.Host, proxy.port(), s.User, s.Password, "", 1,
+		WithHostKeyPolicy(HostKeyInsecure), WithKeepAlive(1), WithReuse(false))
+	if err != nil {
+		t.Fatalf("经过代理连接测试服务失败: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Exec("true", WithHide(true)); err != nil {
+		t.Fatalf("执行命令失败: %v", err)
+	}
+	client := conn.Client
+
+	// 网络中断时 keepalive 超时, 主动关闭连接并标记为断开
+	proxy.paused.Store(true)
+	waitDead(t, conn, 5*time.Second)
+
+	proxy.paused.Store(false)
+	if result, err := conn.Exec("echo ok", WithHide(true)); err != nil || string(result.Stdout) != "ok\n" {
+		t.Fatalf("网络恢复后 Exec 应自动重连, 输出: %q, 错误: %v", result.Stdout, err)
+	}
+	// 重连后内嵌的 sftp 客户端同时更新
+	if conn.Client == client {
+		t.Error("重连后 Client 应更新为新的 sftp 客户端")
+	}
+	if _, err := conn.Getwd(); err != nil {
+		t.Errorf("重连后通过内嵌的 sftp 客户端操作失败: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 19:32:05
+ */
+
+package gossh
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"sync"
+)
+
+// pool 连接缓存, 目标机器、跳板机、认证信息和连接选项都相同的 NewConnection 复用同一条健康的 ssh 连接
+var pool = &connPool{conns: make(map[string]*sshConn)}
+
+type connPool struct {
+	mu    sync.Mutex
+	conns map[string]*sshConn
+}
+
+// poolKey 连接缓存的 key, 格式为 user@host:port, 有跳板机时依次追加 |user@host:port, 最后是认证信息和连接选项的摘要
+// 密码、私钥内容、私钥密码、主机公钥校验策略、known_hosts 文件、超时时间等任意一项不同时都不复用,
+// 避免跳过认证和主机公钥校验, 或使用其他调用方的密码执行 sudo
+func (conn *Connection) poolKey() string {
+	h := sha256.New()
+	write := func(values ...any) {
+		for _, v := range values {
+			fmt.Fprintf(h, "%v\x00", v)
+		}
+	}
+
+	write(conn.Password, conn.Passphrase, conn.HostKeyPolicy, conn.KnownHostsFile, conn.Timeout, conn.KeepAlive)
+	if conn.UseAgent {
+		write("agent", os.Getenv("SSH_AUTH_SOCK"))
+	}
+	for _, keyfile := range conn.keyFiles() {
+		writeKeyFile(h, keyfile)
+	}
+
+	var b strings.Builder
+	b.WriteString(conn.String())
+	for _, hop := range conn.jumpHosts() {
+		fmt.Fprintf(&b, "|%s@%s", hop.User, joinHostPort(hop.Host, hop.Port))
+		write(hop.Password)
+		writeKeyFile(h, hop.KeyFile)
+	}
+	fmt.Fprintf(&b, "|%x", h.Sum(nil))
+	return b.String()
+}
+
+// writeKeyFile 将私钥文件及其证书的路径和内容写入 h, 文件内容变化后不再复用之前的连接
+func writeKeyFile(h hash.Hash, keyfile string) {
+	if keyfile == "" {
+		return
+	}
+	for _, file := range []string{keyfile, keyfile + "-cert.pub"} {
+		data, err := os.ReadFile(file)
+		fmt.Fprintf(h, "%s\x00%x\x00%v\x00", file, sha256.Sum256(data), err)
+	}
+}
+
+// get 返回缓存中的连接并增加引用计数, 不存在时返回 nil
+func (p *connPool) get(key string) *sshConn {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.conns[key]
+	if !ok {
+		return nil
+	}
+	s.refs++
+	return s
+}
+
+// put 将新建的连接放入缓存. 如果并发建立了相同的连接, 返回缓存中已有的连接并增加其引用计数, 新连接由调用方关闭
+func (p *connPool) put(key string, s *sshConn) (*sshConn, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if cached, ok := p.conns[key]; ok {
+		cached.refs++
+		return cached, false
+	}
+	s.key = key
+	p.conns[key] = s
+	return s, true
+}
+
+// remove 将连接从缓存中移除, 已经持有该连接的调用方不受影响
+func (p *connPool) remove(s *sshConn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s.key != "" && p.conns[s.key] == s {
+		delete(p.conns, s.key)
+	}
+}
+
+// release 减少引用计数, 返回是否需要真正关闭连接
+func (p *connPool) release(s *sshConn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if s.refs--; s.refs > 0 {
+		return false
+	}
+	if s.key != "" && p.conns[s.key] == s {
+		delete(p.conns, s.key)
+	}
+	return true
+}
@@ -0,0 +1,157 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-19 14:48:10
+ */
+
+package gossh
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lsne/goutils/utils/gossh/sshtest"
+)
+
+func poolRefs(conn *Connection) (int, bool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	_, cached := pool.conns[conn.shared.key]
+	return conn.shared.refs, cached
+}
+
+func TestPoolReuse(t *testing.T) {
+	s := sshtest.NewServer(t)
+	connect := func(password string, opts ...ConnOption) (*Connection, error) {
+		opts = append([]ConnOption{WithKnownHostsFile(s.KnownHostsFile), WithHostKeyPolicy(HostKeyStrict), WithReuse(true)}, opts...)
+		return NewConnection(s.Host, s.Port, s.User, password, "", 5, opts...)
+	}
+
+	c1, err := connect(s.Password)
+	if err != nil {
+		t.Fatalf("连接测试服务失败: %v", err)
+	}
+	defer c1.Close()
+	c2, err := connect(s.Password)
+	if err != nil {
+		t.Fatalf("连接测试服务失败: %v", err)
+	}
+	defer c2.Close()
+
+	if c1 == c2 || c1.shared != c2.shared || c1.Client == nil || c1.Client != c2.Client {
+		t.Fatal("相同的连接参数应得到不同的 *Connection, 共享同一条 ssh 连接和 sftp 客户端")
+	}
+	if refs, cached := poolRefs(c1); refs != 2 || !cached {
+		t.Fatalf("引用计数应为 2 且在缓存中, 实际: %d, %v", refs, cached)
+	}
+
+	// 认证信息或连接选项不同时不复用, 仍然进行认证和主机公钥校验
+	if c, err := connect("wrong"); err == nil {
+		c.Close()
+		t.Error("错误的密码不应复用缓存中的连接")
+	}
+	emptyKnownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(emptyKnownHosts, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := connect(s.Password, WithKnownHostsFile(emptyKnownHosts)); err == nil {
+		c.Close()
+		t.Error("known_hosts 中没有主机公钥时不应复用缓存中的连接")
+	}
+	c, err := connect(s.Password, WithReuse(false))
+	if err != nil {
+		t.Fatalf("连接测试服务失败: %v", err)
+	}
+	if c.shared == c1.shared || c.Client == c1.Client {
+		t.Error("WithReuse(false) 时不应复用缓存中的连接")
+	}
+	c.Close()
+	c, err = NewConnection(s.Host, s.Port, s.User, s.Password, "", 5, WithKnownHostsFile(s.KnownHostsFile), WithHostKeyPolicy(HostKeyStrict))
+	if err != nil {
+		t.Fatalf("连接测试服务失败: %v", err)
+	}
+	if c.shared == c1.shared {
+		t.Error("默认不应复用缓存中的连接")
+	}
+	c.Close()
+	c3, err := connect(s.Password, WithKeepAlive(-1))
+	if err != nil {
+		t.Fatalf("连接测试服务失败: %v", err)
+	}
+	if c3.shared == c1.shared {
+		t.Error("keepalive 不同时不应复用缓存中的连接")
+	}
+	c3.Close()
+
+	// 同一个调用方重复 Close 只减少一次引用计数
+	if err := c1.Close(); err != nil {
+		t.Fatalf("关闭连接失败: %v", err)
+	}
+	if err := c1.Close(); err != nil {
+		t.Fatalf("重复关闭连接失败: %v", err)
+	}
+	if refs, cached := poolRefs(c2); refs != 1 || !cached {
+		t.Fatalf("重复 Close 后引用计数应为 1 且在缓存中, 实际: %d, %v", refs, cached)
+	}
+	var terr *TransportError
+	if _, err := c1.Exec("true", WithHide(true)); !errors.As(err, &terr) || !errors.Is(terr.Err, ErrClosed) {
+		t.Errorf("Close 后应返回 ErrClosed, 实际: %v", err)
+	}
+	if result, err := c2.Exec("echo ok", WithHide(true)); err != nil || string(result.Stdout) != "ok\n" {
+		t.Fatalf("其他调用方 Close 后应仍可使用, 输出: %q, 错误: %v", result.Stdout, err)
+	}
+
+	// 最后一个调用方 Close 时真正关闭并移出缓存
+	if err := c2.Close(); err != nil {
+		t.Fatalf("关闭连接失败: %v", err)
+	}
+	if refs, cached := poolRefs(c2); refs != 0 || cached {
+		t.Fatalf("全部 Close 后引用计数应为 0 且不在缓存中, 实际: %d, %v", refs, cached)
+	}
+	if c2.IsAlive() {
+		t.Error("全部 Close 后 IsAlive 应为 false")
+	}
+
+	c4, err := connect(s.Password)
+	if err != nil {
+		t.Fatalf("连接测试服务失败: %v", err)
+	}
+	defer c4.Close()
+	if c4.shared == c2.shared {
+		t.Error("已关闭的连接不应被复用")
+	}
+}
+
+func TestPoolKey(t *testing.T) {
+	base := func() *Connection {
+		return &Connection{Host: "10.0.0.1", Port: 22, User: "root", Password: "p", Timeout: 5, KeepAlive: DefaultKeepAlive}
+	}
+	key := base().poolKey()
+
+	for name, modify := range map[string]func(*Connection){
+		"password":       func(c *Connection) { c.Password = "q" },
+		"passphrase":     func(c *Connection) { c.Passphrase = "x" },
+		"hostKeyPolicy":  func(c *Connection) { c.HostKeyPolicy = HostKeyInsecure },
+		"knownHostsFile": func(c *Connection) { c.KnownHostsFile = "/tmp/known_hosts" },
+		"timeout":        func(c *Connection) { c.Timeout = 10 },
+		"keepAlive":      func(c *Connection) { c.KeepAlive = -1 },
+		"jumpHost":       func(c *Connection) { c.JumpHosts = []JumpHost{{Host: "10.0.0.9"}} },
+	} {
+		c := base()
+		modify(c)
+		if c.poolKey() == key {
+			t.Errorf("%s 不同时 poolKey 应不同", name)
+		}
+	}
+
+	// 跳板机端口为 0 和 22, 用户为空和与目标机器相同时是同一台跳板机
+	a, b := base(), base()
+	a.JumpHosts = []JumpHost{{Host: "10.0.0.9"}}
+	b.JumpHosts = []JumpHost{{Host: "10.0.0.9", Port: 22, User: "root"}}
+	if a.poolKey() != b.poolKey() {
+		t.Errorf("跳板机的端口和用户应归一化: %s != %s", a.poolKey(), b.poolKey())
+	}
+}
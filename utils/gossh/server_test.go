@@ -0,0 +1,644 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 10:05:27
+ */
+
+package gossh
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lsne/goutils/utils/gocmd"
+	"github.com/lsne/goutils/utils/gossh/sshtest"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTestConnection(t *testing.T, s *sshtest.Server, password, keyfile string) *Connection {
+	t.Helper()
+
+	conn, err := NewConnection(s.Host, s.Port, s.User, password, keyfile, 5,
+		WithKnownHostsFile(s.KnownHostsFile), WithHostKeyPolicy(HostKeyStrict), WithReuse(false))
+	if err != nil {
+		t.Fatalf("连接测试服务失败: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestServerRun(t *testing.T) {
+	s := sshtest.NewServer(t)
+
+	for name, conn := range map[string]*Connection{
+		"password": newTestConnection(t, s, s.Password, ""),
+		"key":      newTestConnection(t, s, "", s.KeyFile),
+	} {
+		result, err := conn.Exec("echo out; echo err >&2; exit 3", WithHide(true))
+		var exitErr *ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode != 3 {
+			t.Fatalf("%s: 应返回退出码为 3 的 ExitError, 实际: %v", name, err)
+		}
+		// pty 模式下标准错误合并到标准输出
+		if string(result.Stdout) != "out\nerr\n" {
+			t.Errorf("%s: 输出不正确: %q", name, result.Stdout)
+		}
+	}
+
+	// 不申请伪终端时标准输出和标准错误分开
+	if result, err := newTestConnection(t, s, s.Password, "").Exec("echo out; echo err >&2", WithHide(true), WithPty(false)); err != nil || string(result.Stdout) != "out\n" || string(result.Stderr) != "err\n" {
+		t.Errorf("不申请伪终端时输出不正确: %q, %q, %v", result.Stdout, result.Stderr, err)
+	}
+
+	if _, err := NewConnection(s.Host, s.Port, s.User, "wrong", "", 5,
+		WithKnownHostsFile(s.KnownHostsFile), WithHostKeyPolicy(HostKeyStrict), WithReuse(false)); err == nil {
+		t.Error("错误的密码应该连接失败")
+	}
+
+	// KeyFiles 中未提供密码的加密私钥被跳过, 使用其后可用的私钥认证
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKeyWithPassphrase(priv, "", []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	locked := filepath.Join(t.TempDir(), "id_locked")
+	if err := os.WriteFile(locked, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := NewConnection(s.Host, s.Port, s.User, "", "", 5, WithKeyFiles(locked, s.KeyFile),
+		WithKnownHostsFile(s.KnownHostsFile), WithHostKeyPolicy(HostKeyStrict), WithReuse(false)); err != nil {
+		t.Errorf("应跳过加密私钥使用可用的私钥连接, 实际: %v", err)
+	} else {
+		conn.Close()
+	}
+	if _, err := NewConnection(s.Host, s.Port, s.User, "", locked, 5, WithKeyFiles(s.KeyFile),
+		WithKnownHostsFile(s.KnownHostsFile), WithHostKeyPolicy(HostKeyStrict), WithReuse(false)); err == nil {
+		t.Error("明确指定的私钥不可用时应连接失败")
+	}
+
+}
+
+// waitExited 等待 pidFile 中记录的进程退出
+func waitExited(t *testing.T, pidFile string) {
+	t.Helper()
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("读取进程号失败: %v", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatalf("进程号不合法: %q", data)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for syscall.Kill(pid, 0) == nil {
+		if time.Now().After(deadline) {
+			t.Fatalf("取消后远程进程 %d 仍在运行", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestServerRunContext(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	// 超时后终止远程进程, 及时返回已捕获的输出
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	stdout, _, err := conn.RunContext(ctx, "echo $$ > run.pid; echo partial; sleep 30", WithHide(true))
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || !timeoutErr.Timeout() || string(stdout) != "partial\n" {
+		t.Fatalf("应返回超时的 TimeoutError 和部分输出, 实际: %q, %v", stdout, err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("超时后应及时返回, 耗时: %v", time.Since(start))
+	}
+	waitExited(t, filepath.Join(s.Dir, "run.pid"))
+
+	// 调用方取消时 sudo 执行的远程进程同样被终止
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(500*time.Millisecond, cancel)
+	if _, _, err := conn.SudoContext(ctx, "echo $$ > sudo.pid; sleep 30", WithHide(true)); !errors.As(err, &timeoutErr) || timeoutErr.Timeout() || !errors.Is(err, context.Canceled) {
+		t.Fatalf("应返回被取消的 TimeoutError, 实际: %v", err)
+	}
+	waitExited(t, filepath.Join(s.Dir, "sudo.pid"))
+
+	// ctx 已取消时不执行
+	if _, _, err := conn.RunContext(ctx, "touch created", WithHide(true)); !errors.Is(err, context.Canceled) {
+		t.Errorf("ctx 已取消时应返回 context.Canceled, 实际: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(s.Dir, "created")); !os.IsNotExist(err) {
+		t.Error("ctx 已取消时不应执行命令")
+	}
+}
+
+func TestServerSudoAndWatchers(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	result, err := conn.SudoExec(`echo "it's $HOME"`, WithHide(true))
+	if err != nil {
+		t.Fatalf("sudo 执行失败: %v, 输出: %s", err, result.Stdout)
+	}
+	if !strings.HasSuffix(string(result.Stdout), "it's "+s.Dir+"\n") {
+		t.Errorf("sudo 输出不正确: %q", result.Stdout)
+	}
+
+	var sentinelErr *SentinelError
+	if _, err := conn.SudoExec("true", WithHide(true), WithSudoPassword("wrong")); !errors.As(err, &sentinelErr) {
+		t.Errorf("sudo 密码错误应返回 SentinelError, 实际: %v", err)
+	}
+
+	keyConn := newTestConnection(t, s, "", s.KeyFile)
+	var passwordErr *PasswordRequiredError
+	if _, err := keyConn.SudoExec("true", WithHide(true)); !errors.As(err, &passwordErr) {
+		t.Errorf("没有 sudo 密码时应返回 PasswordRequiredError, 实际: %v", err)
+	}
+	if _, err := keyConn.SudoExec("true", WithHide(true), WithSudoPassword(s.Password)); err != nil {
+		t.Errorf("指定 sudo 密码后执行失败: %v", err)
+	}
+
+	result, err = conn.Exec(`printf 'Continue? [y/N] '; read answer; echo "answer: $answer"`, WithHide(true),
+		WithWatchers(Watcher{Pattern: `Continue\? \[y/N\]`, Regexp: true, Response: "y"}))
+	if err != nil || !strings.HasSuffix(string(result.Stdout), "answer: y\n") {
+		t.Errorf("watcher 应答失败: %v, 输出: %q", err, result.Stdout)
+	}
+}
+
+func TestServerAudit(t *testing.T) {
+	s := sshtest.NewServer(t)
+
+	var log bytes.Buffer
+	audit := &gocmd.Audit{DryRun: true, Writer: &log}
+	conn, err := NewConnection(s.Host, s.Port, s.User, s.Password, "", 5,
+		WithKnownHostsFile(s.KnownHostsFile), WithHostKeyPolicy(HostKeyStrict), WithAudit(audit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	local := filepath.Join(t.TempDir(), "a.cnf")
+	if err := os.WriteFile(local, []byte("a=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Scp(local, s.Dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.SudoExec("touch created", WithHide(true)); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.cnf", "created"} {
+		if _, err := os.Stat(filepath.Join(s.Dir, name)); !os.IsNotExist(err) {
+			t.Errorf("演练时不应执行: %s", name)
+		}
+	}
+	if records := audit.Records(); len(records) != 2 || records[0].Command != "scp "+local+" "+s.Host+":"+s.Dir || records[1].User != s.User {
+		t.Errorf("演练记录不正确: %+v", records)
+	}
+
+	// 记录模式下执行命令, 密码被替换
+	audit.DryRun = false
+	if _, err := conn.Exec("echo "+s.Password, WithHide(true)); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(log.String(), s.Password) || !strings.Contains(log.String(), "echo "+gocmd.Redacted) {
+		t.Errorf("审计日志中的密码没有被替换: %s", log.String())
+	}
+}
+
+// snapshot 返回目录下所有文件的路径、权限和内容, 用于比较演练前后是否有变化
+func snapshot(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		var data []byte
+		if info.Mode().IsRegular() {
+			if data, err = os.ReadFile(p); err != nil {
+				return err
+			}
+		}
+		files[p] = info.Mode().String() + " " + string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestServerDryRunChanges(t *testing.T) {
+	s := sshtest.NewServer(t)
+
+	audit := &gocmd.Audit{DryRun: true}
+	conn, err := NewConnection(s.Host, s.Port, s.User, s.Password, "", 5,
+		WithKnownHostsFile(s.KnownHostsFile), WithHostKeyPolicy(HostKeyStrict), WithAudit(audit))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	file := filepath.Join(s.Dir, "my.cnf")
+	if err := os.WriteFile(file, []byte("a=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(s.Dir, "data", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	local := t.TempDir()
+	before, localBefore := snapshot(t, s.Dir), snapshot(t, local)
+
+	// 所有修改文件的操作在演练时都只记录, 不执行
+	changes := map[string]func() error{
+		"WriteFile":       func() error { return conn.WriteFile(file, []byte("a=2\n"), 0600) },
+		"AppendFile":      func() error { return conn.AppendFile(file, []byte("b=1\n"), 0644) },
+		"ChownByName":     func() error { return conn.ChownByName(file, "root", "") },
+		"ChownAllByName":  func() error { return conn.ChownAllByName(s.Dir, "root", "") },
+		"RemoveAll":       func() error { return conn.RemoveAll(filepath.Join(s.Dir, "data")) },
+		"SymlinkForce":    func() error { return conn.SymlinkForce(file, filepath.Join(s.Dir, "link")) },
+		"BackupFile":      func() error { return conn.BackupFile(file) },
+		"MoveToBackup":    func() error { return conn.MoveToBackup(file) },
+		"MkdirAll":        func() error { return conn.MkdirAll(filepath.Join(s.Dir, "new", "dir")) },
+		"Chmod":           func() error { return conn.Chmod(file, 0600) },
+		"Remove":          func() error { return conn.Remove(file) },
+		"PosixRename":     func() error { return conn.PosixRename(file, file+".bak") },
+		"Scp":             func() error { return conn.Scp(s.KeyFile, s.Dir) },
+		"Download":        func() error { return conn.Download(file, local) },
+		"CopyTo":          func() error { return conn.CopyTo(conn, file, filepath.Join(s.Dir, "copy.cnf")) },
+		"Sudo":            func() error { _, err := conn.SudoExec("rm -rf data", WithHide(true)); return err },
+		"Truncate":        func() error { return conn.Truncate(file, 0) },
+		"Chtimes":         func() error { return conn.Chtimes(file, time.Unix(0, 0), time.Unix(0, 0)) },
+		"RemoveDirectory": func() error { return conn.RemoveDirectory(filepath.Join(s.Dir, "data", "sub")) },
+	}
+	for name, change := range changes {
+		if err := change(); err != nil {
+			t.Errorf("%s: 演练时应返回 nil, 实际: %v", name, err)
+		}
+	}
+	if _, err := conn.Create(filepath.Join(s.Dir, "created")); !errors.Is(err, ErrDryRun) {
+		t.Errorf("演练时 Create 应返回 ErrDryRun, 实际: %v", err)
+	}
+
+	if records := audit.Records(); len(records) != len(changes)+1 {
+		t.Errorf("每个操作应只记录一次, 实际记录了 %d 条: %+v", len(records), records)
+	}
+	if after := snapshot(t, s.Dir); fmt.Sprint(after) != fmt.Sprint(before) {
+		t.Errorf("演练时远程文件不应变化:\n%v\n%v", before, after)
+	}
+	if after := snapshot(t, local); fmt.Sprint(after) != fmt.Sprint(localBefore) {
+		t.Errorf("演练时本地文件不应变化: %v", after)
+	}
+
+	// 只读的查询在演练时仍然执行, 不记录
+	n := len(audit.Records())
+	if sum, err := conn.FileMD5(file); err != nil || sum != fmt.Sprintf("%x", md5.Sum([]byte("a=1\n"))) {
+		t.Errorf("演练时 FileMD5 应返回真实结果: %s, %v", sum, err)
+	}
+	if facts, err := conn.Facts(); err != nil || facts.Hostname == "" {
+		t.Errorf("演练时 Facts 应返回真实结果: %+v, %v", facts, err)
+	}
+	if records := audit.Records(); len(records) != n {
+		t.Errorf("只读查询不应记录: %+v", records[n:])
+	}
+
+	// 记录模式下执行, 复合操作只记录一次
+	var log bytes.Buffer
+	audit.DryRun, audit.Writer = false, &log
+	if err := conn.WriteFile(file, []byte("a=2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(file); string(data) != "a=2\n" {
+		t.Errorf("文件内容不正确: %q", data)
+	}
+	if lines := strings.Count(log.String(), "\n"); lines != 1 || !strings.Contains(log.String(), `"command":"write `) {
+		t.Errorf("WriteFile 应只记录一次: %s", log.String())
+	}
+}
+
+func TestServerFacts(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	u, err := user.Current()
+	if err != nil {
+		t.Skipf("获取当前用户失败: %v", err)
+	}
+
+	// 命令在本机执行, 结果应与本机一致
+	facts, err := conn.FactsFor(filepath.Join(s.Dir, "data", "not-exists"), u.Username)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostname, _ := os.ReadFile("/proc/sys/kernel/hostname")
+	if facts.Hostname != strings.TrimSpace(string(hostname)) || facts.CPUs <= 0 || facts.MemTotal == 0 || facts.Arch == "" {
+		t.Errorf("基本信息不正确: %+v", facts)
+	}
+	if facts.DataFree == 0 || !facts.UserExists || facts.User != u.Username {
+		t.Errorf("数据目录或用户信息不正确: %+v", facts)
+	}
+
+	facts, err = conn.FactsFor("", "gossh-no-such-user")
+	if err != nil || facts.UserExists || facts.DataPath != "" || facts.DataFree != 0 {
+		t.Errorf("不存在的用户应返回 false: %+v, %v", facts, err)
+	}
+
+	// FactsFor 不影响 Facts 的缓存
+	if cached, err := conn.Facts(); err != nil || cached.User != "" || cached.Hostname != facts.Hostname {
+		t.Errorf("Facts 的缓存不正确: %+v, %v", cached, err)
+	}
+}
+
+func TestServerTransfer(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	local := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(local, "conf", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"conf/a.cnf": "a=1\n", "conf/sub/b.cnf": "b=2\n"} {
+		if err := os.WriteFile(filepath.Join(local, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 目标目录已存在, 上传到其下的同名目录
+	if err := conn.Scp(filepath.Join(local, "conf"), s.Dir, WithVerify()); err != nil {
+		t.Fatalf("上传目录失败: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(s.Dir, "conf", "sub", "b.cnf")); err != nil || string(data) != "b=2\n" {
+		t.Errorf("上传的文件内容不正确: %q, %v", data, err)
+	}
+
+	download := filepath.Join(t.TempDir(), "backup")
+	if err := conn.Download(filepath.Join(s.Dir, "conf"), download, WithVerify()); err != nil {
+		t.Fatalf("下载目录失败: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(download, "a.cnf")); err != nil || string(data) != "a=1\n" {
+		t.Errorf("下载的文件内容不正确: %q, %v", data, err)
+	}
+
+	remote := filepath.Join(s.Dir, "conf", "a.cnf")
+	if err := conn.WriteFile(remote, []byte("a=2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := conn.ReadFile(remote); err != nil || string(data) != "a=2\n" {
+		t.Errorf("读取远程文件不正确: %q, %v", data, err)
+	}
+
+	if err := conn.ClearDir(filepath.Join(s.Dir, "conf")); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.IsDirEmptyOrNotExists(filepath.Join(s.Dir, "conf")); err != nil {
+		t.Errorf("清空目录失败: %v", err)
+	}
+	if err := conn.ClearDir("/etc"); err == nil {
+		t.Error("不允许清空系统目录")
+	}
+}
+
+func TestServerTransferOptions(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	const size, half = 256 << 10, 128 << 10
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = byte(i % 251)
+	}
+	source := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(source, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(s.Dir, "data")
+
+	// 记录每次传输的第一次和最后一次进度回调
+	var first, last Progress
+	progress := WithProgress(func(p Progress) {
+		if first.File == "" {
+			first = p
+		}
+		last = p
+	})
+	transfer := func(fn func() error) {
+		t.Helper()
+		first, last = Progress{}, Progress{}
+		if err := fn(); err != nil {
+			t.Fatalf("传输失败: %v", err)
+		}
+	}
+
+	transfer(func() error { return conn.Scp(source, target, progress) })
+	if last.File != source || last.Transferred != size || last.Total != size || last.ETA != 0 {
+		t.Errorf("传输完成时的进度不正确: %+v", last)
+	}
+
+	// 已传输的部分与本地文件一致时从断点继续, 不一致时重新传输
+	if err := os.Truncate(target, half); err != nil {
+		t.Fatal(err)
+	}
+	transfer(func() error { return conn.Scp(source, target, WithResume(), WithVerify(), progress) })
+	if first.Transferred <= half {
+		t.Errorf("应从 %d 字节处继续上传, 第一次进度: %+v", half, first)
+	}
+	if err := os.WriteFile(target, make([]byte, half), 0644); err != nil {
+		t.Fatal(err)
+	}
+	transfer(func() error { return conn.Scp(source, target, WithResume(), WithVerify(), progress) })
+	if first.Transferred > half {
+		t.Errorf("已传输的部分不一致时应重新上传, 第一次进度: %+v", first)
+	}
+
+	download := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(download, data[:half], 0644); err != nil {
+		t.Fatal(err)
+	}
+	transfer(func() error { return conn.Download(target, download, WithResume(), WithVerify(), progress) })
+	if first.Transferred <= half {
+		t.Errorf("应从 %d 字节处继续下载, 第一次进度: %+v", half, first)
+	}
+	if err := os.WriteFile(download, make([]byte, half), 0644); err != nil {
+		t.Fatal(err)
+	}
+	transfer(func() error { return conn.Download(target, download, WithResume(), WithVerify(), progress) })
+	if got, _ := os.ReadFile(download); first.Transferred > half || !bytes.Equal(got, data) {
+		t.Errorf("已下载的部分不一致时应重新下载, 第一次进度: %+v", first)
+	}
+
+	// 限速 128KB/s 上传 64KB 至少需要 0.5 秒
+	small := filepath.Join(t.TempDir(), "small")
+	if err := os.WriteFile(small, data[:64<<10], 0644); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	transfer(func() error { return conn.Scp(small, target, WithRateLimit(128<<10)) })
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("限速后传输过快: %v", elapsed)
+	}
+
+	// md5 不申请伪终端, 标准错误不会混入输出
+	if sum, err := conn.FileMD5(target); err != nil || sum != fmt.Sprintf("%x", md5.Sum(data[:64<<10])) {
+		t.Errorf("md5 不正确: %s, %v", sum, err)
+	}
+	if sum, err := conn.FileMD5(filepath.Join(s.Dir, "missing")); err == nil {
+		t.Errorf("文件不存在时应返回错误, 实际: %s", sum)
+	}
+}
+
+// newEchoServer 启动原样返回收到的数据的 TCP 服务, 返回监听地址
+func newEchoServer(t *testing.T) string {
+	t.Helper()
+
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = echo.Close() })
+	go func() {
+		for {
+			c, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_, _ = io.Copy(c, c)
+			}()
+		}
+	}()
+	return echo.Addr().String()
+}
+
+// pingTunnel 经由隧道的监听地址发送数据, 检查原样返回
+func pingTunnel(t *testing.T, addr string) {
+	t.Helper()
+
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(c, buf); err != nil || string(buf) != "ping" {
+		t.Errorf("经由隧道读取的数据不正确: %q, %v", buf, err)
+	}
+}
+
+func TestServerLocalForward(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	tunnel, err := conn.LocalForward("", newEchoServer(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tunnel.Close()
+
+	pingTunnel(t, tunnel.Listen)
+	if err := tunnel.Close(); err != nil {
+		t.Errorf("关闭隧道失败: %v", err)
+	}
+}
+
+func TestServerRemoteForward(t *testing.T) {
+	s := sshtest.NewServer(t)
+	conn := newTestConnection(t, s, s.Password, "")
+
+	// 在"远程机器"上监听随机端口, 转发到本机的 echo 服务
+	tunnel, err := conn.RemoteForward("127.0.0.1:0", newEchoServer(t))
+	if err != nil {
+		t.Fatalf("远程端口转发失败: %v", err)
+	}
+	defer tunnel.Close()
+	if tunnel.Port() == 0 {
+		t.Fatalf("应返回远程机器上实际监听的端口: %s", tunnel.Listen)
+	}
+
+	pingTunnel(t, tunnel.Listen)
+	if err := tunnel.Close(); err != nil {
+		t.Errorf("关闭隧道失败: %v", err)
+	}
+
+	// 关闭后远程机器上不再监听
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c, err := net.Dial("tcp", tunnel.Listen)
+		if err != nil {
+			break
+		}
+		c.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("关闭隧道后远程机器上仍在监听")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestServerGroup(t *testing.T) {
+	s1, s2 := sshtest.NewServer(t), sshtest.NewServer(t)
+	c1, c2 := newTestConnection(t, s1, s1.Password, ""), newTestConnection(t, s2, s2.Password, "")
+	g, err := NewGroup(c1, c2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 未设置 WithHide(true) 时, 标准输出的每一行加上机器前缀打印到终端
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	results, err := g.Run(context.Background(), "echo hello; printf world")
+	os.Stdout = stdout
+	w.Close()
+	printed, _ := io.ReadAll(r)
+	if err != nil || len(results) != 2 || string(results[c2.String()].Stdout) != "hello\nworld" {
+		t.Fatalf("在所有机器上执行命令失败: %v, %+v", err, results)
+	}
+	// 不同机器的输出按行交错
+	lines := strings.Split(strings.TrimSuffix(string(printed), "\n"), "\n")
+	slices.Sort(lines)
+	if want := []string{"[" + s1.Host + "] hello", "[" + s1.Host + "] hello", "[" + s1.Host + "] world", "[" + s1.Host + "] world"}; !slices.Equal(lines, want) {
+		t.Errorf("终端输出不正确: %q", printed)
+	}
+
+	// ctx 取消时中断正在进行的传输
+	source := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(source, bytes.Repeat([]byte("x"), 1<<20), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var once sync.Once
+	_, err = g.Scp(ctx, source, filepath.Join(s1.Dir, "data"), WithRateLimit(1<<20), WithProgress(func(Progress) { once.Do(cancel) }))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ctx 取消后应中断传输, 实际: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-19 10:12:48
+ */
+
+package gossh
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lsne/goutils/utils/strutil"
+
+	"github.com/pkg/sftp"
+)
+
+// ErrDryRun 演练模式下以写方式打开远程文件时返回, 操作已记录到 Audit 但没有执行
+var ErrDryRun = errors.New("演练模式, 没有执行")
+
+// 以下方法与 sftp.Client 的同名方法相同, 每次调用时获取当前的 sftp 客户端, 连接已断开时先重连
+// 修改文件的方法经过 change 演练或记录, 记录的命令为等价的 shell 命令
+
+// SFTP 返回当前的 sftp 客户端, 用于 Connection 没有封装的 sftp 操作, 如 Walk
+// 断线重连会关闭旧的客户端, 不要保存返回值, 每次使用前重新获取
+func (conn *Connection) SFTP() (*sftp.Client, error) {
+	return conn.sftp()
+}
+
+func (conn *Connection) Stat(p string) (os.FileInfo, error) {
+	client, err := conn.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return client.Stat(p)
+}
+
+func (conn *Connection) Lstat(p string) (os.FileInfo, error) {
+	client, err := conn.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return client.Lstat(p)
+}
+
+func (conn *Connection) ReadDir(p string) ([]os.FileInfo, error) {
+	client, err := conn.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return client.ReadDir(p)
+}
+
+func (conn *Connection) ReadLink(p string) (string, error) {
+	client, err := conn.sftp()
+	if err != nil {
+		return "", err
+	}
+	return client.ReadLink(p)
+}
+
+func (conn *Connection) RealPath(p string) (string, error) {
+	client, err := conn.sftp()
+	if err != nil {
+		return "", err
+	}
+	return client.RealPath(p)
+}
+
+func (conn *Connection) Getwd() (string, error) {
+	client, err := conn.sftp()
+	if err != nil {
+		return "", err
+	}
+	return client.Getwd()
+}
+
+func (conn *Connection) Glob(pattern string) ([]string, error) {
+	client, err := conn.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return client.Glob(pattern)
+}
+
+func (conn *Connection) Open(p string) (*sftp.File, error) {
+	client, err := conn.sftp()
+	if err != nil {
+		return nil, err
+	}
+	return client.Open(p)
+}
+
+// Create 与 sftp.Client.Create 相同, 演练时返回 ErrDryRun
+func (conn *Connection) Create(p string) (*sftp.File, error) {
+	return conn.openWrite(p, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+}
+
+// OpenFile 与 sftp.Client.OpenFile 相同, 以写方式打开时经过 change, 演练时返回 ErrDryRun
+func (conn *Connection) OpenFile(p string, f int) (*sftp.File, error) {
+	if f&(os.O_WRONLY|os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_TRUNC) == 0 {
+		client, err := conn.sftp()
+		if err != nil {
+			return nil, err
+		}
+		return client.OpenFile(p, f)
+	}
+	return conn.openWrite(p, f)
+}
+
+func (conn *Connection) openWrite(p string, f int) (*sftp.File, error) {
+	var file *sftp.File
+	cmd := "write " + strutil.Quote(p)
+	if f&os.O_APPEND != 0 {
+		cmd = "append " + strutil.Quote(p)
+	}
+	err := conn.change(cmd, func(client *sftp.Client) (err error) {
+		file, err = client.OpenFile(p, f)
+		return err
+	})
+	if err == nil && file == nil {
+		return nil, ErrDryRun
+	}
+	return file, err
+}
+
+func (conn *Connection) Mkdir(p string) error {
+	return conn.change("mkdir "+strutil.Quote(p), func(client *sftp.Client) error {
+		return client.Mkdir(p)
+	})
+}
+
+func (conn *Connection) MkdirAll(p string) error {
+	return conn.change("mkdir -p "+strutil.Quote(p), func(client *sftp.Client) error {
+		return client.MkdirAll(p)
+	})
+}
+
+func (conn *Connection) Chmod(p string, mode os.FileMode) error {
+	return conn.change(fmt.Sprintf("chmod %#o %s", mode.Perm(), strutil.Quote(p)), func(client *sftp.Client) error {
+		return client.Chmod(p, mode)
+	})
+}
+
+func (conn *Connection) Chown(p string, uid, gid int) error {
+	return conn.change(fmt.Sprintf("chown %d:%d %s", uid, gid, strutil.Quote(p)), func(client *sftp.Client) error {
+		return client.Chown(p, uid, gid)
+	})
+}
+
+func (conn *Connection) Chtimes(p string, atime time.Time, mtime time.Time) error {
+	return conn.change(fmt.Sprintf("touch -m -d @%d %s", mtime.Unix(), strutil.Quote(p)), func(client *sftp.Client) error {
+		return client.Chtimes(p, atime, mtime)
+	})
+}
+
+func (conn *Connection) Truncate(p string, size int64) error {
+	return conn.change(fmt.Sprintf("truncate -s %d %s", size, strutil.Quote(p)), func(client *sftp.Client) error {
+		return client.Truncate(p, size)
+	})
+}
+
+func (conn *Connection) Remove(p string) error {
+	return conn.change("rm "+strutil.Quote(p), func(client *sftp.Client) error {
+		return client.Remove(p)
+	})
+}
+
+func (conn *Connection) RemoveDirectory(p string) error {
+	return conn.change("rmdir "+strutil.Quote(p), func(client *sftp.Client) error {
+		return client.RemoveDirectory(p)
+	})
+}
+
+func (conn *Connection) Rename(oldname, newname string) error {
+	return conn.change("mv -n "+strutil.Quote(oldname)+" "+strutil.Quote(newname), func(client *sftp.Client) error {
+		return client.Rename(oldname, newname)
+	})
+}
+
+func (conn *Connection) PosixRename(oldname, newname string) error {
+	return conn.change("mv "+strutil.Quote(oldname)+" "+strutil.Quote(newname), func(client *sftp.Client) error {
+		return client.PosixRename(oldname, newname)
+	})
+}
+
+func (conn *Connection) Symlink(oldname, newname string) error {
+	return conn.change("ln -s "+strutil.Quote(oldname)+" "+strutil.Quote(newname), func(client *sftp.Client) error {
+		return client.Symlink(oldname, newname)
+	})
+}
+
+func (conn *Connection) Link(oldname, newname string) error {
+	return conn.change("ln "+strutil.Quote(oldname)+" "+strutil.Quote(newname), func(client *sftp.Client) error {
+		return client.Link(oldname, newname)
+	})
+}
@@ -0,0 +1,229 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 09:12:35
+ */
+
+// Package sshtest 提供用于单元测试的进程内 ssh + sftp 服务端
+// 命令在本机以当前用户执行, 工作目录和 sftp 的根目录都是一个临时目录
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// 默认的登录用户名和密码, 密码同时也是 sudo 密码
+const (
+	DefaultUser     = "tester"
+	DefaultPassword = "sshtest-password"
+)
+
+// Server 进程内的 ssh 服务端, 支持密码和公钥认证、pty 请求、exec、sftp 子系统、direct-tcpip 和 tcpip-forward 端口转发
+// pty 模式下命令的标准错误合并到标准输出, 与真实的终端一致
+type Server struct {
+	Host           string
+	Port           uint16
+	User           string
+	Password       string
+	Dir            string // 临时目录, 作为命令的工作目录、HOME 和 sftp 的工作目录
+	KeyFile        string // 已授权的 ed25519 私钥文件(未加密)
+	KnownHostsFile string // 包含本服务端公钥的 known_hosts 文件
+
+	listener   net.Listener
+	config     *ssh.ServerConfig
+	authorized ssh.PublicKey
+	binDir     string // 假的 sudo 所在目录, 加到命令的 PATH 最前面
+
+	mu     sync.Mutex
+	conns  map[*ssh.ServerConn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewServer 在 127.0.0.1 的随机端口上启动服务端, 测试结束时自动关闭
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+
+	s, err := newServer(t.TempDir(), t.TempDir())
+	if err != nil {
+		t.Fatalf("启动 ssh 测试服务失败: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func newServer(dir string, confDir string) (*Server, error) {
+	s := &Server{
+		User:     DefaultUser,
+		Password: DefaultPassword,
+		Dir:      dir,
+		binDir:   filepath.Join(confDir, "bin"),
+		conns:    make(map[*ssh.ServerConn]struct{}),
+	}
+
+	hostSigner, err := generateSigner(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	s.KeyFile = filepath.Join(confDir, "id_ed25519")
+	clientSigner, err := generateSigner(&s.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	s.authorized = clientSigner.PublicKey()
+
+	if err := os.MkdirAll(s.binDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(s.binDir, "sudo"), []byte(sudoScript), 0755); err != nil {
+		return nil, err
+	}
+
+	s.config = &ssh.ServerConfig{
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if meta.User() == s.User && string(password) == s.Password {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("用户 %s 密码错误", meta.User())
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if meta.User() == s.User && string(key.Marshal()) == string(s.authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("用户 %s 公钥未授权", meta.User())
+		},
+	}
+	s.config.AddHostKey(hostSigner)
+
+	if s.listener, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+		return nil, err
+	}
+	addr := s.listener.Addr().(*net.TCPAddr)
+	s.Host, s.Port = addr.IP.String(), uint16(addr.Port)
+
+	s.KnownHostsFile = filepath.Join(confDir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(s.Addr())}, hostSigner.PublicKey())
+	if err := os.WriteFile(s.KnownHostsFile, []byte(line+"\n"), 0600); err != nil {
+		_ = s.listener.Close()
+		return nil, err
+	}
+
+	s.wg.Go(s.serve)
+	return s, nil
+}
+
+// generateSigner 生成 ed25519 密钥, file 不为 nil 时将私钥写入该文件
+func generateSigner(file *string) (ssh.Signer, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if file != nil {
+		block, err := ssh.MarshalPrivateKey(key, "sshtest")
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(*file, pem.EncodeToMemory(block), 0600); err != nil {
+			return nil, err
+		}
+	}
+	return ssh.NewSignerFromKey(key)
+}
+
+// Addr 返回 host:port
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.Host, strconv.Itoa(int(s.Port)))
+}
+
+// CloseConnections 断开所有客户端连接, 服务端继续监听, 用于测试断线重连
+func (s *Server) CloseConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+}
+
+// Close 停止监听并断开所有客户端连接
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	err := s.listener.Close()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Go(func() {
+			s.handleConn(nc)
+		})
+	}
+}
+
+func (s *Server) handleConn(nc net.Conn) {
+	sc, chans, reqs, err := ssh.NewServerConn(nc, s.config)
+	if err != nil {
+		_ = nc.Close()
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		_ = sc.Close()
+		return
+	}
+	s.conns[sc] = struct{}{}
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, sc)
+		s.mu.Unlock()
+		_ = sc.Close()
+	}()
+
+	go handleGlobalRequests(sc, reqs)
+
+	var wg sync.WaitGroup
+	for nch := range chans {
+		switch nch.ChannelType() {
+		case "session":
+			wg.Go(func() { s.handleSession(nch) })
+		case "direct-tcpip":
+			wg.Go(func() { handleDirectTCPIP(nch) })
+		default:
+			_ = nch.Reject(ssh.UnknownChannelType, "不支持的 channel 类型")
+		}
+	}
+	wg.Wait()
+}
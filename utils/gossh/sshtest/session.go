@@ -0,0 +1,357 @@
+//go:build unix
+
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 09:31:04
+ */
+
+package sshtest
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// sudoScript 假的 sudo, 支持 -S -n -p -H -u 参数, 从标准输入读取密码并与 SSHTEST_SUDO_PASSWORD 比较
+// 与真实的 sudo 一样, 提示和错误信息输出到标准错误, 最多尝试 3 次, -n 时直接失败. 命令以当前用户执行
+const sudoScript = `#!/bin/sh
+prompt='[sudo] password for '"$USER"': '
+while [ $# -gt 0 ]; do
+	case "$1" in
+		-p) prompt=$2; shift 2 ;;
+		-n) echo "sudo: a password is required" >&2; exit 1 ;;
+		-u) shift 2 ;;
+		--) shift; break ;;
+		-*) shift ;;
+		*) break ;;
+	esac
+done
+tries=0
+while [ $tries -lt 3 ]; do
+	printf '%s' "$prompt" >&2
+	IFS= read -r password || exit 1
+	if [ "$password" = "$SSHTEST_SUDO_PASSWORD" ]; then
+		exec "$@"
+	fi
+	echo "Sorry, try again." >&2
+	tries=$((tries + 1))
+done
+echo "sudo: 3 incorrect password attempts" >&2
+exit 1
+`
+
+var signals = map[ssh.Signal]syscall.Signal{
+	ssh.SIGHUP:  syscall.SIGHUP,
+	ssh.SIGINT:  syscall.SIGINT,
+	ssh.SIGKILL: syscall.SIGKILL,
+	ssh.SIGQUIT: syscall.SIGQUIT,
+	ssh.SIGTERM: syscall.SIGTERM,
+	ssh.SIGUSR1: syscall.SIGUSR1,
+	ssh.SIGUSR2: syscall.SIGUSR2,
+}
+
+type session struct {
+	server  *Server
+	channel ssh.Channel
+	pty     bool
+	env     []string
+
+	mu      sync.Mutex
+	cmd     *exec.Cmd
+	started bool
+}
+
+func (s *Server) handleSession(nch ssh.NewChannel) {
+	channel, reqs, err := nch.Accept()
+	if err != nil {
+		return
+	}
+	sess := &session{server: s, channel: channel}
+	defer sess.kill(syscall.SIGKILL)
+	defer channel.Close()
+
+	for req := range reqs {
+		switch req.Type {
+		case "pty-req":
+			sess.pty = true
+			_ = req.Reply(true, nil)
+		case "env":
+			var kv struct{ Name, Value string }
+			if err := ssh.Unmarshal(req.Payload, &kv); err != nil {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			sess.env = append(sess.env, kv.Name+"="+kv.Value)
+			_ = req.Reply(true, nil)
+		case "exec":
+			var payload struct{ Command string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || !sess.start() {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			go sess.exec(payload.Command)
+		case "subsystem":
+			var payload struct{ Name string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err != nil || payload.Name != "sftp" || !sess.start() {
+				_ = req.Reply(false, nil)
+				continue
+			}
+			_ = req.Reply(true, nil)
+			go sess.sftp()
+		case "signal":
+			var payload struct{ Signal string }
+			if err := ssh.Unmarshal(req.Payload, &payload); err == nil {
+				if sig, ok := signals[ssh.Signal(payload.Signal)]; ok {
+					sess.kill(sig)
+				}
+			}
+			if req.WantReply {
+				_ = req.Reply(true, nil)
+			}
+		default:
+			// 不支持 shell 等交互式请求
+			_ = req.Reply(false, nil)
+		}
+	}
+}
+
+// start 每个会话只能执行一次 exec 或 subsystem
+func (sess *session) start() bool {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.started {
+		return false
+	}
+	sess.started = true
+	return true
+}
+
+func (sess *session) exec(command string) {
+	s := sess.server
+	cmd := exec.Command("/bin/sh", "-c", command)
+	cmd.Dir = s.Dir
+	cmd.Env = append(os.Environ(),
+		"HOME="+s.Dir,
+		"USER="+s.User,
+		"PATH="+s.binDir+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"SSHTEST_SUDO_PASSWORD="+s.Password,
+	)
+	cmd.Env = append(cmd.Env, sess.env...)
+	// 放到单独的进程组, 收到信号时连同子进程一起终止
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	cmd.Stdout = sess.channel
+	if sess.pty {
+		cmd.Stderr = sess.channel
+	} else {
+		cmd.Stderr = sess.channel.Stderr()
+	}
+
+	// 客户端可能一直不关闭标准输入, 不能直接赋值给 cmd.Stdin, 否则 Wait 会等待复制结束
+	stdin, err := cmd.StdinPipe()
+	if err == nil {
+		sess.mu.Lock()
+		err = cmd.Start()
+		if err == nil {
+			sess.cmd = cmd
+		}
+		sess.mu.Unlock()
+	}
+	if err != nil {
+		_, _ = io.WriteString(sess.channel.Stderr(), err.Error()+"\n")
+		sess.exit(127, "")
+		return
+	}
+
+	go func() {
+		_, _ = io.Copy(stdin, sess.channel)
+		_ = stdin.Close()
+	}()
+
+	err = cmd.Wait()
+	sess.mu.Lock()
+	sess.cmd = nil
+	sess.mu.Unlock()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		sess.exit(0, "")
+	case errors.As(err, &exitErr):
+		if ws, ok := exitErr.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			sess.exit(-1, signalName(ws.Signal()))
+		} else {
+			sess.exit(exitErr.ExitCode(), "")
+		}
+	default:
+		sess.exit(255, "")
+	}
+}
+
+// exit 发送退出码或终止信号并关闭 channel
+func (sess *session) exit(code int, signal string) {
+	if signal != "" {
+		_, _ = sess.channel.SendRequest("exit-signal", false, ssh.Marshal(struct {
+			Signal     string
+			CoreDumped bool
+			Error      string
+			Lang       string
+		}{Signal: signal}))
+	} else {
+		_, _ = sess.channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{uint32(code)}))
+	}
+	_ = sess.channel.Close()
+}
+
+// kill 向命令所在的进程组发送信号
+func (sess *session) kill(sig syscall.Signal) {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+	if sess.cmd != nil {
+		_ = syscall.Kill(-sess.cmd.Process.Pid, sig)
+	}
+}
+
+func (sess *session) sftp() {
+	server, err := sftp.NewServer(sess.channel, sftp.WithServerWorkingDirectory(sess.server.Dir))
+	if err == nil {
+		_ = server.Serve()
+		_ = server.Close()
+	}
+	_ = sess.channel.Close()
+}
+
+func signalName(sig syscall.Signal) string {
+	for name, s := range signals {
+		if s == sig {
+			return string(name)
+		}
+	}
+	return strconv.Itoa(int(sig))
+}
+
+// handleDirectTCPIP 处理客户端的本地端口转发(ssh -L)请求
+func handleDirectTCPIP(nch ssh.NewChannel) {
+	var payload struct {
+		Host       string
+		Port       uint32
+		OriginHost string
+		OriginPort uint32
+	}
+	if err := ssh.Unmarshal(nch.ExtraData(), &payload); err != nil {
+		_ = nch.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	target, err := net.Dial("tcp", net.JoinHostPort(payload.Host, strconv.Itoa(int(payload.Port))))
+	if err != nil {
+		_ = nch.Reject(ssh.ConnectionFailed, err.Error())
+		return
+	}
+
+	channel, reqs, err := nch.Accept()
+	if err != nil {
+		_ = target.Close()
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	pipe(channel, target)
+}
+
+// forwardRequest tcpip-forward 和 cancel-tcpip-forward 请求的内容
+type forwardRequest struct {
+	Addr string
+	Port uint32
+}
+
+// handleGlobalRequests 处理客户端的远程端口转发(ssh -R)请求, 连接断开时关闭所有监听
+func handleGlobalRequests(sc *ssh.ServerConn, reqs <-chan *ssh.Request) {
+	listeners := make(map[forwardRequest]net.Listener)
+	defer func() {
+		for _, l := range listeners {
+			_ = l.Close()
+		}
+	}()
+
+	for req := range reqs {
+		var payload forwardRequest
+		if req.Type != "tcpip-forward" && req.Type != "cancel-tcpip-forward" || ssh.Unmarshal(req.Payload, &payload) != nil {
+			if req.WantReply {
+				_ = req.Reply(false, nil)
+			}
+			continue
+		}
+
+		if req.Type == "cancel-tcpip-forward" {
+			l, ok := listeners[payload]
+			if ok {
+				_ = l.Close()
+				delete(listeners, payload)
+			}
+			_ = req.Reply(ok, nil)
+			continue
+		}
+
+		l, err := net.Listen("tcp", net.JoinHostPort(payload.Addr, strconv.Itoa(int(payload.Port))))
+		if err != nil {
+			_ = req.Reply(false, nil)
+			continue
+		}
+		// 端口为 0 时使用随机端口, 客户端按实际端口取消转发和匹配转发的连接
+		payload.Port = uint32(l.Addr().(*net.TCPAddr).Port)
+		listeners[payload] = l
+		go serveForward(sc, l, payload)
+		_ = req.Reply(true, ssh.Marshal(struct{ Port uint32 }{payload.Port}))
+	}
+}
+
+// serveForward 将监听到的连接通过 forwarded-tcpip channel 转发给客户端
+func serveForward(sc *ssh.ServerConn, l net.Listener, bind forwardRequest) {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			origin := c.RemoteAddr().(*net.TCPAddr)
+			channel, reqs, err := sc.OpenChannel("forwarded-tcpip", ssh.Marshal(struct {
+				Addr       string
+				Port       uint32
+				OriginAddr string
+				OriginPort uint32
+			}{bind.Addr, bind.Port, origin.IP.String(), uint32(origin.Port)}))
+			if err != nil {
+				_ = c.Close()
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			pipe(channel, c)
+		}()
+	}
+}
+
+// pipe 在 channel 和 TCP 连接之间双向复制数据, 一端结束后关闭另一端的写入, 都结束后关闭
+func pipe(channel ssh.Channel, c net.Conn) {
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		_, _ = io.Copy(c, channel)
+		if tc, ok := c.(*net.TCPConn); ok {
+			_ = tc.CloseWrite()
+		}
+	})
+	_, _ = io.Copy(channel, c)
+	_ = channel.CloseWrite()
+	wg.Wait()
+	_ = channel.Close()
+	_ = c.Close()
+}
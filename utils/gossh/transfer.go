@@ -0,0 +1,282 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-17 20:15:27
+ */
+
+package gossh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lsne/goutils/utils/fileutil"
+	"github.com/lsne/goutils/utils/strutil"
+
+	"github.com/pkg/sftp"
+)
+
+// 进度回调的最小间隔, 每个文件传输完成时一定会回调一次
+const progressInterval = 500 * time.Millisecond
+
+// Progress 文件传输进度
+type Progress struct {
+	File        string        // 正在传输的本地文件
+	Transferred int64         // 已传输的字节数, 包含断点续传时跳过的部分
+	Total       int64         // 文件总字节数
+	Rate        float64       // 本次传输的平均速率, 字节/秒
+	ETA         time.Duration // 预计剩余时间
+}
+
+type TransferOptions struct {
+	ctx       context.Context
+	progress  func(Progress)
+	resume    bool
+	verify    bool
+	preserve  bool
+	rateLimit int64
+}
+
+// TransferOption 是一个函数类型，用于修改 TransferOptions
+type TransferOption func(*TransferOptions)
+
+// WithContext 返回一个 TransferOption，ctx 取消时中断传输并返回 ctx 的错误, 已传输的部分保留, 可以使用 WithResume 继续传输
+func WithContext(ctx context.Context) TransferOption {
+	return func(o *TransferOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithProgress 返回一个 TransferOption，用于设置传输进度回调
+func WithProgress(fn func(Progress)) TransferOption {
+	return func(o *TransferOptions) {
+		o.progress = fn
+	}
+}
+
+// WithResume 返回一个 TransferOption，目标文件已存在、不大于源文件, 且与源文件相同长度部分的 md5 一致时, 从目标文件的大小处继续传输
+// md5 不一致(如源文件已修改)时重新传输
+func WithResume() TransferOption {
+	return func(o *TransferOptions) {
+		o.resume = true
+	}
+}
+
+// WithVerify 返回一个 TransferOption，上传完成后比较本地和远程文件的 md5
+func WithVerify() TransferOption {
+	return func(o *TransferOptions) {
+		o.verify = true
+	}
+}
+
+// WithPreserve 返回一个 TransferOption，保留本地文件的权限和修改时间
+func WithPreserve() TransferOption {
+	return func(o *TransferOptions) {
+		o.preserve = true
+	}
+}
+
+// WithRateLimit 返回一个 TransferOption，限制每个文件的传输速率(字节/秒), 小于等于 0 时不限速
+func WithRateLimit(bytesPerSecond int64) TransferOption {
+	return func(o *TransferOptions) {
+		o.rateLimit = bytesPerSecond
+	}
+}
+
+func newTransferOptions(opts []TransferOption) *TransferOptions {
+	options := &TransferOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// wrap 根据 ctx、限速和进度回调选项包装本地文件的 Reader, 都未设置时直接返回 r, 保留 sftp 的并发写优化
+func (o *TransferOptions) wrap(r io.Reader, file string, offset, total int64) io.Reader {
+	if o.ctx != nil {
+		r = &ctxReader{ctx: o.ctx, r: r}
+	}
+	if o.rateLimit > 0 {
+		r = &rateLimitReader{r: r, rate: o.rateLimit, start: time.Now()}
+	}
+	if o.progress != nil {
+		r = &progressReader{r: r, fn: o.progress, file: file, offset: offset, transferred: offset, total: total, start: time.Now()}
+	}
+	return r
+}
+
+// resumeOffset 返回上传时断点续传的起始位置, 不需要续传或已传输的部分与本地文件 source 不一致时返回 0
+func (conn *Connection) resumeOffset(client *sftp.Client, source, target string, size int64, options *TransferOptions) int64 {
+	if !options.resume {
+		return 0
+	}
+	info, err := client.Stat(target)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > size {
+		return 0
+	}
+	if !conn.sameHead(source, target, info.Size()) {
+		return 0
+	}
+	return info.Size()
+}
+
+// downloadResumeOffset 返回下载时断点续传的起始位置, 不需要续传或已下载的部分与远程文件 source 不一致时返回 0
+func (conn *Connection) downloadResumeOffset(source, target string, size int64, options *TransferOptions) int64 {
+	if !options.resume {
+		return 0
+	}
+	info, err := os.Stat(target)
+	if err != nil || !info.Mode().IsRegular() || info.Size() == 0 || info.Size() > size {
+		return 0
+	}
+	if !conn.sameHead(target, source, info.Size()) {
+		return 0
+	}
+	return info.Size()
+}
+
+// sameHead 本地文件 local 和远程文件 remote 的前 n 个字节是否相同
+func (conn *Connection) sameHead(local, remote string, n int64) bool {
+	localSum, err := fileutil.FileHeadMD5(local, n)
+	if err != nil {
+		return false
+	}
+	remoteSum, err := conn.headMD5(remote, n)
+	return err == nil && localSum == remoteSum
+}
+
+// finishTransfer 上传完成后校验 md5, 保留权限和修改时间
+func (conn *Connection) finishTransfer(client *sftp.Client, source, target string, info os.FileInfo, options *TransferOptions) error {
+	if options.verify {
+		if err := conn.verifyMD5(source, target); err != nil {
+			return err
+		}
+	}
+
+	if options.preserve {
+		if err := client.Chmod(target, info.Mode().Perm()); err != nil {
+			return err
+		}
+		if err := client.Chtimes(target, info.ModTime(), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyMD5 比较本地文件 local 和远程文件 remote 的 md5
+func (conn *Connection) verifyMD5(local, remote string) error {
+	localSum, err := fileutil.FileMD5(local)
+	if err != nil {
+		return fmt.Errorf("计算本地文件(%s)的 md5 失败: %v", local, err)
+	}
+
+	remoteSum, err := conn.FileMD5(remote)
+	if err != nil {
+		return err
+	}
+
+	if localSum != remoteSum {
+		return fmt.Errorf("在机器: %s 上, 文件(%s)的 md5(%s)与本地文件(%s)的 md5(%s)不一致", conn.Host, remote, remoteSum, local, localSum)
+	}
+	return nil
+}
+
+// FileMD5 计算远程文件的 md5
+func (conn *Connection) FileMD5(file string) (string, error) {
+	return conn.md5sum(fmt.Sprintf("md5sum %s", strutil.Quote(file)))
+}
+
+// headMD5 计算远程文件前 n 个字节的 md5
+func (conn *Connection) headMD5(file string, n int64) (string, error) {
+	return conn.md5sum(fmt.Sprintf("head -c %d %s | md5sum", n, strutil.Quote(file)))
+}
+
+// md5sum 执行输出格式同 md5sum 的命令, 返回第一列. 不申请伪终端, 避免标准错误混入输出
+func (conn *Connection) md5sum(cmd string) (string, error) {
+	result, err := conn.Exec(cmd, WithHide(true), WithReadOnly(true), WithPty(false))
+	if err != nil {
+		return "", fmt.Errorf(GOSSH_ERR_FORMAT, conn.Host, cmd, err, result.Stdout, result.Stderr)
+	}
+
+	fields := strings.Fields(string(result.Stdout))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("在机器: %s 上, 执行(%s)没有输出", conn.Host, cmd)
+	}
+	return fields[0], nil
+}
+
+// progressReader 读取时统计传输进度, 最多每 progressInterval 回调一次, 读到 EOF 时回调一次
+type progressReader struct {
+	r           io.Reader
+	fn          func(Progress)
+	file        string
+	offset      int64
+	transferred int64
+	total       int64
+	start       time.Time
+	last        time.Time
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.transferred += int64(n)
+
+	now := time.Now()
+	if err == io.EOF || now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.fn(p.progress(now))
+	}
+	return n, err
+}
+
+func (p *progressReader) progress(now time.Time) Progress {
+	pg := Progress{File: p.file, Transferred: p.transferred, Total: p.total}
+	if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+		pg.Rate = float64(p.transferred-p.offset) / elapsed
+	}
+	if pg.Rate > 0 && p.total > p.transferred {
+		pg.ETA = time.Duration(float64(p.total-p.transferred) / pg.Rate * float64(time.Second))
+	}
+	return pg
+}
+
+// ctxReader ctx 取消后读取时返回 ctx 的错误
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(b)
+}
+
+// rateLimitReader 限制读取速率, 读取速度超过限制时 sleep
+type rateLimitReader struct {
+	r     io.Reader
+	rate  int64
+	start time.Time
+	read  int64
+}
+
+func (l *rateLimitReader) Read(b []byte) (int, error) {
+	// 每次最多读取 1/10 秒的数据量, 避免突发流量
+	if chunk := max(l.rate/10, 1); int64(len(b)) > chunk {
+		b = b[:chunk]
+	}
+
+	n, err := l.r.Read(b)
+	l.read += int64(n)
+
+	expected := time.Duration(float64(l.read) / float64(l.rate) * float64(time.Second))
+	if wait := expected - time.Since(l.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, err
+}
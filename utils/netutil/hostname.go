@@ -66,6 +66,7 @@ func ResolveHostnameOrIP(host string) bool {
 	return ResolveHostname(host)
 }
 
+// SplitHostnameOrIPList 拆分逗号分隔的主机列表, 需要展开范围、CIDR 或排除主机时使用 ExpandHosts
 func SplitHostnameOrIPList(s string) (hosts []string, err error) {
 	if s == "" {
 		return hosts, nil
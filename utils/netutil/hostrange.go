@@ -0,0 +1,212 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 17:15:48
+ */
+
+package netutil
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MaxHostRange 一个字符串最多展开的主机数
+const MaxHostRange = 65536
+
+// 方括号中只包含数字、逗号和连字符时才作为范围展开
+var rangePattern = regexp.MustCompile(`^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$`)
+
+// ExpandHostRange 展开字符串中方括号表示的数字范围, 如 10.0.0.[1-3] 展开为 10.0.0.1, 10.0.0.2, 10.0.0.3
+// 支持逗号分隔的列表 [1,3,5-7], 起始数字有前导零时按其宽度补零, 如 db[01-12]; 有多个范围时按笛卡尔积展开
+// 方括号中不是数字范围时原样保留, 如 IPv6 地址 [::1]:22
+func ExpandHostRange(s string) ([]string, error) {
+	hosts, err := expandRange(s)
+	if err != nil {
+		return nil, fmt.Errorf("展开主机范围(%s)失败: %v", s, err)
+	}
+	return hosts, nil
+}
+
+func expandRange(s string) ([]string, error) {
+	start, end := -1, -1
+	for i := 0; i < len(s); i++ {
+		if s[i] != '[' {
+			continue
+		}
+		j := strings.IndexByte(s[i:], ']')
+		if j < 0 {
+			break
+		}
+		if rangePattern.MatchString(s[i+1 : i+j]) {
+			start, end = i, i+j
+			break
+		}
+		i += j
+	}
+	if start < 0 {
+		return []string{s}, nil
+	}
+
+	items, err := rangeItems(s[start+1 : end])
+	if err != nil {
+		return nil, err
+	}
+	rest, err := expandRange(s[end+1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(items)*len(rest) > MaxHostRange {
+		return nil, fmt.Errorf("展开后超过 %d 个", MaxHostRange)
+	}
+
+	hosts := make([]string, 0, len(items)*len(rest))
+	for _, item := range items {
+		for _, r := range rest {
+			hosts = append(hosts, s[:start]+item+r)
+		}
+	}
+	return hosts, nil
+}
+
+// rangeItems 展开 1,3,5-7 格式的列表
+func rangeItems(s string) ([]string, error) {
+	var items []string
+	for item := range strings.SplitSeq(s, ",") {
+		from, to, isRange := strings.Cut(item, "-")
+		if !isRange {
+			items = append(items, item)
+			continue
+		}
+
+		a, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, err
+		}
+		b, err := strconv.Atoi(to)
+		if err != nil {
+			return nil, err
+		}
+		if a > b {
+			return nil, fmt.Errorf("范围(%s)的起始值大于结束值", item)
+		}
+		if b-a >= MaxHostRange || len(items)+b-a >= MaxHostRange {
+			return nil, fmt.Errorf("展开后超过 %d 个", MaxHostRange)
+		}
+
+		width := 0
+		if len(from) > 1 && from[0] == '0' {
+			width = len(from)
+		}
+		for n := a; n <= b; n++ {
+			items = append(items, fmt.Sprintf("%0*d", width, n))
+		}
+	}
+	return items, nil
+}
+
+// ExpandHosts 展开以逗号或空白分隔的主机列表, 返回按出现顺序去重后的主机, 每个主机都必须是合法的主机名或 IP
+// 每一项可以是:
+//   - 主机名或 IP
+//   - ExpandHostRange 支持的范围, 如 10.1.2.[10-20], db[01-12].example.com, 范围中的逗号不作为分隔符
+//   - CIDR, 如 10.1.2.0/28, IPv4 前缀小于 31 时不包括网络地址和广播地址
+//   - 以 ! 开头的排除项, 格式同上, 无论出现在什么位置, 都从结果中去掉
+func ExpandHosts(s string) ([]string, error) {
+	var hosts []string
+	excludes := make(map[string]bool)
+	for _, item := range splitHostList(s) {
+		exclude := strings.HasPrefix(item, "!")
+		expanded, err := expandHostItem(strings.TrimPrefix(item, "!"))
+		if err != nil {
+			return nil, err
+		}
+		if exclude {
+			for _, host := range expanded {
+				excludes[host] = true
+			}
+		} else {
+			hosts = append(hosts, expanded...)
+		}
+		if len(hosts) > MaxHostRange {
+			return nil, fmt.Errorf("主机列表(%s)展开后超过 %d 个", s, MaxHostRange)
+		}
+	}
+
+	result := make([]string, 0, len(hosts))
+	seen := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		if !seen[host] && !excludes[host] {
+			seen[host] = true
+			result = append(result, host)
+		}
+	}
+	return result, nil
+}
+
+// expandHostItem 展开一项 CIDR 或范围, 并校验展开后的每个主机
+func expandHostItem(item string) ([]string, error) {
+	if strings.Contains(item, "/") {
+		return expandCIDR(item)
+	}
+
+	hosts, err := ExpandHostRange(item)
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		if !ValidHostnameOrIP(host) {
+			return nil, fmt.Errorf(" (%s) IP地址或主机名格式不合法", host)
+		}
+	}
+	return hosts, nil
+}
+
+func expandCIDR(s string) ([]string, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return nil, fmt.Errorf(" (%s) 不是合法的 CIDR: %v", s, err)
+	}
+	prefix = prefix.Masked()
+
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("CIDR(%s)包含的地址超过 %d 个", s, MaxHostRange)
+	}
+
+	var hosts []string
+	for addr := prefix.Addr(); addr.IsValid() && prefix.Contains(addr); addr = addr.Next() {
+		hosts = append(hosts, addr.String())
+	}
+	// IPv4 的网络地址和广播地址不是主机, /31 和 /32 除外
+	if prefix.Addr().Is4() && hostBits > 1 {
+		hosts = hosts[1 : len(hosts)-1]
+	}
+	return hosts, nil
+}
+
+// splitHostList 按逗号或空白拆分, 不拆分方括号中的逗号
+func splitHostList(s string) []string {
+	var items []string
+	depth, start := 0, 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			switch c := s[i]; {
+			case c == '[':
+				depth++
+				continue
+			case c == ']':
+				depth = max(depth-1, 0)
+				continue
+			case depth > 0 || (c != ',' && c != ' ' && c != '\t' && c != '\n' && c != '\r'):
+				continue
+			}
+		}
+		if item := strings.TrimSpace(s[start:i]); item != "" {
+			items = append(items, item)
+		}
+		start = i + 1
+	}
+	return items
+}
@@ -0,0 +1,142 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 18:50:37
+ */
+
+package netutil
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/lsne/goutils/utils/fileutil"
+	"gopkg.in/ini.v1"
+)
+
+// Inventory 按组划分的主机清单, 通过 LoadInventory 从 yaml 或 ini 文件加载
+//
+// yaml 格式:
+//
+//	groups:
+//	  - name: mysql
+//	    hosts: ["10.1.2.[10-20]", "!10.1.2.15"]
+//	    vars: {port: 3306, role: master}
+//
+// ini 格式, 每个 [group] 是一个组, hosts 的格式同 ExpandHosts, vars 为逗号分隔的 key=value:
+//
+//	[group]
+//	name = mysql
+//	hosts = 10.1.2.[10-20], !10.1.2.15
+//	vars = port=3306, role=master
+type Inventory struct {
+	Groups []InventoryGroup
+}
+
+// InventoryGroup 一组主机及其变量
+type InventoryGroup struct {
+	Name  string
+	Hosts []string // 展开并去重后的主机
+	Vars  map[string]string
+}
+
+// inventoryFile 清单文件的结构, yaml 中 hosts 和 vars 是列表和字典, ini 中是字符串
+type inventoryFile struct {
+	Groups []inventoryFileGroup `yaml:"groups" ini:"group,nonunique"`
+}
+
+type inventoryFileGroup struct {
+	Name     string            `yaml:"name" ini:"name"`
+	Hosts    []string          `yaml:"hosts" ini:"-"`
+	Vars     map[string]string `yaml:"vars" ini:"-"`
+	HostList string            `yaml:"-" ini:"hosts"`
+	VarList  string            `yaml:"-" ini:"vars"`
+}
+
+// LoadInventory 根据扩展名(.yaml, .yml, .ini)加载主机清单, 展开每个组的主机并校验, 组名不能为空或重复
+func LoadInventory(filename string) (*Inventory, error) {
+	var f inventoryFile
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".yaml", ".yml":
+		if err := fileutil.YAMLLoadFromFile(filename, &f); err != nil {
+			return nil, fmt.Errorf("加载主机清单(%s)失败: %v", filename, err)
+		}
+	case ".ini":
+		if err := fileutil.INILoadFromFile(filename, &f, ini.LoadOptions{AllowNonUniqueSections: true}); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("主机清单(%s)的格式(%s)不支持, 只支持 yaml 和 ini", filename, ext)
+	}
+
+	inv := &Inventory{}
+	for _, g := range f.Groups {
+		if g.Name == "" {
+			return nil, fmt.Errorf("主机清单(%s)中有组没有设置 name", filename)
+		}
+		if _, ok := inv.Group(g.Name); ok {
+			return nil, fmt.Errorf("主机清单(%s)中的组(%s)重复", filename, g.Name)
+		}
+
+		hosts, err := ExpandHosts(strings.Join(append(g.Hosts, g.HostList), ","))
+		if err != nil {
+			return nil, fmt.Errorf("主机清单(%s)中的组(%s): %v", filename, g.Name, err)
+		}
+
+		vars := make(map[string]string, len(g.Vars))
+		maps.Copy(vars, g.Vars)
+		for kv := range strings.SplitSeq(g.VarList, ",") {
+			if kv = strings.TrimSpace(kv); kv == "" {
+				continue
+			}
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok {
+				return nil, fmt.Errorf("主机清单(%s)中的组(%s)的变量(%s)格式不合法, 应为 key=value", filename, g.Name, kv)
+			}
+			vars[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+
+		inv.Groups = append(inv.Groups, InventoryGroup{Name: g.Name, Hosts: hosts, Vars: vars})
+	}
+	return inv, nil
+}
+
+// Group 返回指定名称的组
+func (inv *Inventory) Group(name string) (*InventoryGroup, bool) {
+	for i := range inv.Groups {
+		if inv.Groups[i].Name == name {
+			return &inv.Groups[i], true
+		}
+	}
+	return nil, false
+}
+
+// Hosts 返回所有组中的主机, 按出现顺序去重
+func (inv *Inventory) Hosts() []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, g := range inv.Groups {
+		for _, host := range g.Hosts {
+			if !seen[host] {
+				seen[host] = true
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts
+}
+
+// HostVars 返回主机所在的所有组的变量, 主机属于多个组时, 后面的组中的同名变量覆盖前面的
+func (inv *Inventory) HostVars(host string) map[string]string {
+	vars := make(map[string]string)
+	for _, g := range inv.Groups {
+		for _, h := range g.Hosts {
+			if h == host {
+				maps.Copy(vars, g.Vars)
+				break
+			}
+		}
+	}
+	return vars
+}
@@ -0,0 +1,93 @@
+/*
+ * @Author: lsne
+ * @Date: 2026-10-18 19:05:12
+ */
+
+package netutil
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandHosts(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"10.1.2.[10-12]", []string{"10.1.2.10", "10.1.2.11", "10.1.2.12"}},
+		{"db[01-02,10].example.com", []string{"db01.example.com", "db02.example.com", "db10.example.com"}},
+		{"10.1.2.0/30, !10.1.2.2", []string{"10.1.2.1"}},
+		{"a b,a\tc", []string{"a", "b", "c"}},
+	}
+	for _, c := range cases {
+		got, err := ExpandHosts(c.in)
+		if err != nil {
+			t.Fatalf("展开(%q)失败: %v", c.in, err)
+		}
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("展开(%q)的结果不正确: %v, 应为: %v", c.in, got, c.want)
+		}
+	}
+
+	for _, in := range []string{"10.1.2.[5-1]", "bad_host", "10.0.0.0/8"} {
+		if _, err := ExpandHosts(in); err == nil {
+			t.Errorf("展开(%q)应返回错误", in)
+		}
+	}
+}
+
+func TestLoadInventory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"hosts.yaml": `groups:
+  - name: mysql
+    hosts: ["10.1.2.[10-12]", "!10.1.2.11"]
+    vars: {port: 3306, role: master}
+  - name: redis
+    hosts: [10.1.2.10]
+    vars: {port: 6379}
+`,
+		"hosts.ini": `[group]
+name = mysql
+hosts = 10.1.2.[10-12], !10.1.2.11
+vars = port=3306, role=master
+
+[group]
+name = redis
+hosts = 10.1.2.10
+vars = port=6379
+`,
+	}
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		inv, err := LoadInventory(file)
+		if err != nil {
+			t.Fatalf("%s: 加载主机清单失败: %v", name, err)
+		}
+		g, ok := inv.Group("mysql")
+		if !ok || !reflect.DeepEqual(g.Hosts, []string{"10.1.2.10", "10.1.2.12"}) {
+			t.Errorf("%s: mysql 组不正确: %+v", name, g)
+		}
+		if got := inv.Hosts(); !reflect.DeepEqual(got, []string{"10.1.2.10", "10.1.2.12"}) {
+			t.Errorf("%s: 所有主机不正确: %v", name, got)
+		}
+		want := map[string]string{"port": "6379", "role": "master"}
+		if got := inv.HostVars("10.1.2.10"); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: 主机变量不正确: %v, 应为: %v", name, got, want)
+		}
+	}
+
+	dup := filepath.Join(dir, "dup.yaml")
+	if err := os.WriteFile(dup, []byte("groups:\n  - name: a\n  - name: a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadInventory(dup); err == nil {
+		t.Error("组名重复时应返回错误")
+	}
+}
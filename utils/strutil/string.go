@@ -157,6 +157,14 @@ func Quote(s string) string {
 	return "'" + strings.ReplaceAll(s, "'", "'\"'\"'") + "'"
 }
 
+// ansiPattern 匹配 ANSI 转义序列: CSI(颜色、光标移动等)、OSC(设置终端标题等) 和其他单字符转义
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+
+// StripANSI 去掉字符串中的 ANSI 转义序列(颜色、光标控制等)
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
 // ResolveLogPath 根据 base 目录和日志路径，返回日志的绝对路径。
 // - 如果 logfile 是绝对路径，直接返回；
 // - 否则，将其解析为相对于 dir 的路径，并返回规范化后的绝对路径。